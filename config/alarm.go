@@ -0,0 +1,99 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+type AlarmAction string
+
+const (
+	AlarmDisplay AlarmAction = "DISPLAY"
+	AlarmAudio   AlarmAction = "AUDIO"
+	AlarmEmail   AlarmAction = "EMAIL"
+)
+
+// Alarm configures the VALARM added by the ALARM action, or the alarms removed by REMOVE_ALARMS
+type Alarm struct {
+	Action AlarmAction `yaml:"action,omitempty"`
+	// Trigger is a duration relative to the start (or end, see Related) of the event, or
+	// an absolute UTC date-time such as 20240304T090000Z
+	Trigger string `yaml:"trigger,omitempty"`
+	// Related is START or END
+	Related     string   `yaml:"related,omitempty"`
+	Repeat      int      `yaml:"repeat,omitempty"`
+	Duration    string   `yaml:"duration,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Summary     string   `yaml:"summary,omitempty"`
+	Attendees   []string `yaml:"attendees,omitempty"`
+	Attach      string   `yaml:"attach,omitempty"`
+	// Rules select the existing alarms REMOVE_ALARMS removes, all are removed when empty
+	Rules []Rule `yaml:"rules,omitempty"`
+}
+
+// IsAbsoluteTrigger reports whether the trigger is a UTC date-time rather than a duration
+func IsAbsoluteTrigger(trigger string) bool {
+	_, err := time.Parse("20060102T150405Z", trigger)
+	return err == nil
+}
+
+func (a *Alarm) Validate() error {
+	var errs ValidationErrors
+
+	switch a.Action {
+	case "", AlarmDisplay, AlarmAudio, AlarmEmail:
+	default:
+		errs.add("action", "action must be one of %s, %s or %s", AlarmDisplay, AlarmAudio, AlarmEmail)
+	}
+
+	if a.Trigger != "" && !IsAbsoluteTrigger(a.Trigger) {
+		if _, err := ParseDuration(a.Trigger); err != nil {
+			errs.add("trigger", "trigger must be a duration or a UTC date-time: %s", err)
+		}
+	}
+
+	switch strings.ToUpper(a.Related) {
+	case "", "START", "END":
+	default:
+		errs.add("related", "related must be START or END")
+	}
+	if a.Related != "" && IsAbsoluteTrigger(a.Trigger) {
+		errs.add("related", "related cannot be used with an absolute trigger")
+	}
+
+	if a.Repeat < 0 {
+		errs.add("repeat", "repeat must not be negative")
+	}
+	if (a.Repeat > 0) != (a.Duration != "") {
+		errs.add("duration", "repeat and duration must be set together")
+	}
+	if a.Duration != "" {
+		if _, err := ParseDuration(a.Duration); err != nil {
+			errs.add("duration", "%s", err)
+		}
+	}
+
+	for i, rule := range a.Rules {
+		errs.nest(indexPath("rules", i), rule.Validate())
+	}
+
+	return errs.err()
+}
+
+// RequireModifierAlarm ensures the modifier describes an alarm that can be added
+func RequireModifierAlarm(m *Modifier) ValidationErrors {
+	var errs ValidationErrors
+	if m.Alarm == nil || m.Alarm.Trigger == "" {
+		errs = append(errs, RequireModifierDuration(m)...)
+	}
+
+	if m.Alarm != nil && m.Alarm.Action == AlarmEmail {
+		if len(m.Alarm.Attendees) == 0 {
+			errs.nest("alarm", FieldError("attendees", "email alarms require attendees"))
+		}
+		if m.Alarm.Summary == "" {
+			errs.nest("alarm", FieldError("summary", "email alarms require a summary"))
+		}
+	}
+	return errs
+}
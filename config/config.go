@@ -1,12 +1,14 @@
 package config
 
 import (
-	"fmt"
 	"net/url"
 	"os"
 	"slices"
 	"strings"
+	"text/template"
 
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,14 +18,110 @@ type Rule struct {
 	Check         string   `yaml:"check"`
 	CaseSensitive bool     `yaml:"case"`
 	Data          []string `yaml:"data,omitempty"`
+	// DataFile and DataUrl point to extra data, reloaded on every heartbeat
+	DataFile string `yaml:"data_file,omitempty"`
+	DataUrl  string `yaml:"data_url,omitempty"`
+
+	// Matching options applied to both the event and the data before comparing
+	Normalize          Normalization `yaml:"normalize,omitempty"`
+	StripAccents       bool          `yaml:"strip_accents,omitempty"`
+	CollapseWhitespace bool          `yaml:"collapse_whitespace,omitempty"`
+	// Distance is the maximum edit distance for fuzzy checks, 1 when not set
+	Distance *int `yaml:"distance,omitempty"`
+
+	// Geo is used by location based checks
+	Geo *Geo `yaml:"geo,omitempty"`
+
+	// Source and Match are used by checks depending on the events of another source
+	Source string `yaml:"source,omitempty"`
+	Match  []Rule `yaml:"match,omitempty"`
 }
 
+// MaxDistance returns the maximum edit distance for fuzzy checks, defaulting to 1
+func (r *Rule) MaxDistance() int {
+	if r.Distance == nil {
+		return 1
+	}
+	return *r.Distance
+}
+
+func (r *Rule) Validate() error {
+	var errs ValidationErrors
+
+	if r.Check == "" {
+		errs.add("check", "check is missing")
+		return errs.err()
+	}
+
+	if !HasCheck(r.Check) {
+		errs.add("check", "check %q is unknown", r.Check)
+		return errs.err()
+	}
+
+	if r.Component != "" && !IsProperty(r.Component) {
+		errs.add("component", "component %q is not a valid ICS property", r.Component)
+	}
+
+	switch r.Normalize {
+	case "", NFC, NFKC:
+	default:
+		errs.add("normalize", "normalize must be one of %s or %s", NFC, NFKC)
+	}
+
+	if r.Distance != nil && *r.Distance < 0 {
+		errs.add("distance", "distance must not be negative")
+	}
+
+	if r.Geo != nil {
+		errs.nest("geo", r.Geo.Validate())
+	}
+
+	if r.DataFile != "" {
+		if _, err := os.Stat(r.DataFile); err != nil {
+			errs.add("data_file", "data_file is not readable: %s", err)
+		}
+	}
+
+	if r.DataUrl != "" {
+		if u, err := url.Parse(r.DataUrl); err != nil || u.Hostname() == "" {
+			errs.add("data_url", "data_url is invalid")
+		}
+	}
+
+	for _, validate := range checkValidators(r.Check) {
+		errs = append(errs, validate(r)...)
+	}
+
+	for i, match := range r.Match {
+		errs.nest(indexPath("match", i), match.Validate())
+	}
+
+	return errs.dedupe().err()
+}
+
+// Transform prepares s for comparison according to the matching options of the rule
 func (r *Rule) Transform(s string) string {
+	switch r.Normalize {
+	case NFC:
+		s = norm.NFC.String(s)
+	case NFKC:
+		s = norm.NFKC.String(s)
+	}
+
+	if r.StripAccents {
+		s = stripAccents(s)
+	}
+
+	if r.CollapseWhitespace {
+		s = strings.Join(strings.Fields(s), " ")
+	}
+
 	if r.CaseSensitive {
 		return s
 	}
 
-	return strings.ToLower(s)
+	// a Caser is stateful, so a new one is needed for every call
+	return cases.Fold().String(s)
 }
 
 type Action string
@@ -33,6 +131,44 @@ const (
 	REPLACE Action = "REPLACE"
 	PREPEND Action = "PREPEND"
 	ALARM   Action = "ALARM"
+	// SUBSTITUTE replaces matches of Pattern with Data, which may refer to capture groups
+	SUBSTITUTE Action = "SUBSTITUTE"
+	// TEMPLATE renders Data as a text/template over the fields of the event
+	TEMPLATE Action = "TEMPLATE"
+	// SET creates or overwrites the component
+	SET Action = "SET"
+	// REMOVE removes every instance of the component
+	REMOVE Action = "REMOVE"
+	// DEFAULT sets the component only if the event does not have it yet
+	DEFAULT Action = "DEFAULT"
+	// REMOVE_PARAM removes the parameter named in Data from every instance of the component
+	REMOVE_PARAM Action = "REMOVE_PARAM"
+	// REMOVE_ALARMS removes the existing alarms of the event
+	REMOVE_ALARMS Action = "REMOVE_ALARMS"
+	// SHIFT moves the event by the duration in Data
+	SHIFT Action = "SHIFT"
+	// DURATION sets the length of the event to the duration in Data
+	DURATION Action = "DURATION"
+	// SNAP widens the event to the grid of the duration in Data
+	SNAP Action = "SNAP"
+	// TO_ALL_DAY turns a timed event into an all-day event covering the same days
+	TO_ALL_DAY Action = "TO_ALL_DAY"
+	// TO_TIMED turns an all-day event into a timed event using the time range in Data
+	TO_TIMED Action = "TO_TIMED"
+	// CLAMP limits the event to the time range in Data
+	CLAMP Action = "CLAMP"
+	// SPLIT splits events crossing midnight into one event per day
+	SPLIT Action = "SPLIT"
+	// COALESCE merges overlapping events, or events at most the duration in Data apart
+	COALESCE Action = "COALESCE"
+	// MAP rewrites the component through the lookup table in Map
+	MAP Action = "MAP"
+	// TRAVEL adds a block of the duration in Data before events at another location than the previous event of the day
+	TRAVEL Action = "TRAVEL"
+	// PREP adds a block of the duration in Data, ending Before before the event
+	PREP Action = "PREP"
+	// HOOK passes the events to the long-running command in Hook, which returns them modified
+	HOOK Action = "HOOK"
 )
 
 type NotificationService string
@@ -41,12 +177,89 @@ const (
 	NotifyDiscord NotificationService = "DISCORD"
 )
 
+// Flow decides whether the next modifiers are applied after a modifier has been applied
+type Flow string
+
+const (
+	FlowContinue Flow = "continue"
+	FlowStop     Flow = "stop"
+)
+
+// Modifier applies its action to events passing all of its rules. Events failing any
+// of the rules are passed to the modifiers in Else instead.
 type Modifier struct {
-	Name      string `yaml:"name"`
-	Component string `yaml:"component,omitempty"`
-	Action    Action `yaml:"action"`
-	Data      string `yaml:"data"`
-	Filters   []Rule `yaml:"rules,omitempty"`
+	Name      string     `yaml:"name"`
+	Component string     `yaml:"component,omitempty"`
+	Action    Action     `yaml:"action"`
+	Data      string     `yaml:"data"`
+	Pattern   string     `yaml:"pattern,omitempty"`
+	Alarm     *Alarm     `yaml:"alarm,omitempty"`
+	Map       *Mapping   `yaml:"map,omitempty"`
+	Before    string     `yaml:"before,omitempty"`  // time between synthetic events and their event
+	Summary   string     `yaml:"summary,omitempty"` // template for the summary of synthetic events
+	Hook      *Exec      `yaml:"hook,omitempty"`
+	Filters   []Rule     `yaml:"rules,omitempty"`
+	Flow      Flow       `yaml:"flow,omitempty"`
+	Else      []Modifier `yaml:"else,omitempty"`
+}
+
+func (m *Modifier) Validate() error {
+	var errs ValidationErrors
+
+	if m.Action == "" {
+		errs.add("action", "action is missing")
+	} else if !HasAction(m.Action) {
+		errs.add("action", "action %q is unknown", m.Action)
+	} else {
+		if m.Component != "" && !IsProperty(m.Component) {
+			errs.add("component", "component %q is not a valid ICS property", m.Component)
+		}
+		for _, validate := range actionValidators(m.Action) {
+			errs = append(errs, validate(m)...)
+		}
+	}
+
+	switch m.Flow {
+	case "", FlowContinue, FlowStop:
+	default:
+		errs.add("flow", "flow must be %s or %s", FlowContinue, FlowStop)
+	}
+
+	if m.Alarm != nil {
+		errs.nest("alarm", m.Alarm.Validate())
+	}
+
+	if m.Map != nil {
+		errs.nest("map", m.Map.Validate())
+	}
+
+	if m.Hook != nil {
+		errs.nest("hook", m.Hook.Validate())
+	}
+
+	if m.Before != "" {
+		if d, err := ParseDuration(m.Before); err != nil {
+			errs.add("before", "%s", err)
+		} else if d < 0 {
+			errs.add("before", "before must not be negative")
+		}
+	}
+
+	if m.Summary != "" {
+		if _, err := template.New("summary").Parse(m.Summary); err != nil {
+			errs.add("summary", "summary is invalid: %s", err)
+		}
+	}
+
+	for i, filter := range m.Filters {
+		errs.nest(indexPath("rules", i), filter.Validate())
+	}
+
+	for i, other := range m.Else {
+		errs.nest(indexPath("else", i), other.Validate())
+	}
+
+	return errs.dedupe().err()
 }
 
 type Config struct {
@@ -73,15 +286,25 @@ func LoadConfig(filePath string) (*Config, error) {
 		return nil, e
 	}
 
-	if err := yaml.Unmarshal(content, &config); err != nil {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
 		return nil, err
 	}
 
+	if len(root.Content) > 0 {
+		if err := root.Decode(config); err != nil {
+			return nil, err
+		}
+	}
+
 	if config.Port == "" {
 		config.Port = defaultConfig.Port
 	}
 
 	if err := config.Validate(); err != nil {
+		if errs, ok := err.(ValidationErrors); ok {
+			errs.locate(&root)
+		}
 		return nil, err
 	}
 
@@ -89,28 +312,29 @@ func LoadConfig(filePath string) (*Config, error) {
 }
 
 func (c *Config) Validate() error {
-	var endpoints []string
+	var (
+		errs      ValidationErrors
+		endpoints []string
+	)
 
 	// Validate notification if set - not required
 	if c.Notification != (Notification{}) {
-		if err := c.Notification.Validate(); err != nil {
-			return fmt.Errorf(".Notification: %s", err)
-		}
+		errs.nest("notification", c.Notification.Validate())
 	}
 
 	for i, source := range c.Sources {
+		path := indexPath("sources", i)
+
 		// Ensure that the endpoint is unique
 		if slices.Contains(endpoints, source.EndPoint) {
-			return fmt.Errorf(".Source.%d: EndPoint is not unique", i)
+			errs.nest(path, FieldError("end_point", "end_point is not unique"))
 		}
 		endpoints = append(endpoints, source.EndPoint)
 
-		if err := source.Validate(); err != nil {
-			return fmt.Errorf(".Source.%d: %s", i, err)
-		}
+		errs.nest(path, source.Validate())
 	}
 
-	return nil
+	return errs.err()
 }
 
 type Notification struct {
@@ -119,12 +343,15 @@ type Notification struct {
 }
 
 func (n *Notification) Validate() error {
+	var errs ValidationErrors
+
 	if n.Url == "" {
-		return fmt.Errorf("url is missing")
+		errs.add("url", "url is missing")
 	}
 
 	if n.Service == "" {
-		return fmt.Errorf("service is missing")
+		errs.add("service", "service is missing")
+		return errs.err()
 	}
 
 	n.Service = strings.ToUpper(n.Service)
@@ -132,10 +359,10 @@ func (n *Notification) Validate() error {
 	case NotifyDiscord:
 		break
 	default:
-		return fmt.Errorf("service is invalid")
+		errs.add("service", "service is invalid")
 	}
 
-	return nil
+	return errs.err()
 }
 
 type Source struct {
@@ -143,46 +370,206 @@ type Source struct {
 	Heartbeat int          `yaml:"heartbeat"`
 	Name      string       `yaml:"xwr_name"`
 	Info      []SourceInfo `yaml:"info"`
+	Conflicts Conflicts    `yaml:"conflicts,omitempty"`
+	Privacy   Privacy      `yaml:"privacy,omitempty"`
+	// Calendar sets the RFC 7986 properties of the merged calendar
+	Calendar CalendarMetadata `yaml:"calendar,omitempty"`
+
+	// Rules and Modifiers are applied to the events of all sources together, after
+	// those of the sources themselves
+	Rules     []Rule     `yaml:"rules,omitempty"`
+	Modifiers []Modifier `yaml:"modifiers,omitempty"`
+}
+
+// Conflicts configures the overlap analysis of an endpoint
+type Conflicts struct {
+	Enabled    bool `yaml:"enabled"`
+	SameSource bool `yaml:"same_source"`
 }
 
 func (c *Source) Validate() error {
+	var errs ValidationErrors
+
 	if c.Heartbeat <= 0 {
-		return fmt.Errorf("heartbeat must be greater than 0")
+		errs.add("heartbeat", "heartbeat must be greater than 0")
+	}
+
+	errs.nest("privacy", c.Privacy.Validate())
+	errs.nest("calendar", c.Calendar.Validate())
+
+	names := make([]string, len(c.Info))
+	for i, info := range c.Info {
+		names[i] = info.Name
 	}
 
 	for i, info := range c.Info {
-		if err := info.Validate(); err != nil {
-			return fmt.Errorf(".Info.%d: %s", i, err)
+		path := indexPath("info", i)
+		errs.nest(path, info.Validate())
+
+		for _, ref := range info.sourceRefs() {
+			if !slices.Contains(names, ref.source) {
+				errs.nest(joinPath(path, ref.path), FieldError("source", "depends on unknown source %q", ref.source))
+			} else if c.dependsOn(ref.source, info.Name, nil) {
+				errs.nest(joinPath(path, ref.path), FieldError("source", "circular dependency on source %q", ref.source))
+			}
 		}
 	}
 
-	return nil
+	for i, rule := range c.Rules {
+		errs.nest(indexPath("rules", i), rule.Validate())
+	}
+
+	for i, modifier := range c.Modifiers {
+		errs.nest(indexPath("modifiers", i), modifier.Validate())
+	}
+
+	merged := SourceInfo{Rules: c.Rules, Modifiers: c.Modifiers}
+	for _, ref := range merged.sourceRefs() {
+		if !slices.Contains(names, ref.source) {
+			errs.nest(ref.path, FieldError("source", "depends on unknown source %q", ref.source))
+		}
+	}
+
+	return errs.err()
+}
+
+// dependsOn reports whether source from (transitively) depends on source to
+func (c *Source) dependsOn(from, to string, seen []string) bool {
+	if from == to {
+		return true
+	}
+	if slices.Contains(seen, from) {
+		return false
+	}
+	seen = append(seen, from)
+
+	for _, info := range c.Info {
+		if info.Name != from {
+			continue
+		}
+		for _, dep := range info.Dependencies() {
+			if c.dependsOn(dep, to, seen) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 type SourceInfo struct {
-	Name      string     `yaml:"name"`
-	Url       string     `yaml:"url"`
+	Name string     `yaml:"name"`
+	Type SourceType `yaml:"type,omitempty"`
+	Url  string     `yaml:"url,omitempty"`
+	// Format is the format of the calendar at Url or printed by Exec, Csv and Json map the
+	// fields of their formats
+	Format Format `yaml:"format,omitempty"`
+	Csv    *Csv   `yaml:"csv,omitempty"`
+	Json   *Json  `yaml:"json,omitempty"`
+	// Events, Weeks, Terms, Holidays, VCard, Mail and Exec are used by the other source types
+	Events   []StaticEvent `yaml:"events,omitempty"`
+	Weeks    *Weeks        `yaml:"weeks,omitempty"`
+	Terms    []Term        `yaml:"terms,omitempty"`
+	Holidays *Holidays     `yaml:"holidays,omitempty"`
+	VCard    *VCard        `yaml:"vcard,omitempty"`
+	Mail     *Mail         `yaml:"mail,omitempty"`
+	Exec     *Exec         `yaml:"exec,omitempty"`
+
 	Rules     []Rule     `yaml:"rules,omitempty"`
 	Modifiers []Modifier `yaml:"modifiers,omitempty"`
+	// Privacy overrides the privacy level of the endpoint
+	Privacy *Privacy `yaml:"privacy,omitempty"`
+
+	// Color and Categories are set on every event of the source, Tag is prefixed
+	// to their summary as "[Tag] "
+	Color      string   `yaml:"color,omitempty"`
+	Categories []string `yaml:"categories,omitempty"`
+	Tag        string   `yaml:"tag,omitempty"`
+}
+
+// Kind returns the type of the source, defaulting to url
+func (c *SourceInfo) Kind() SourceType {
+	if c.Type == "" {
+		return SourceUrl
+	}
+	return c.Type
+}
+
+// Dependencies returns the names of the other sources the rules and modifiers depend on
+func (c *SourceInfo) Dependencies() []string {
+	var deps []string
+	for _, ref := range c.sourceRefs() {
+		if !slices.Contains(deps, ref.source) {
+			deps = append(deps, ref.source)
+		}
+	}
+	return deps
+}
+
+// sourceRef is a rule depending on another source, path points to the rule
+type sourceRef struct {
+	path   string
+	source string
+}
+
+// sourceRefs returns every rule of the rules and modifiers that depends on another source
+func (c *SourceInfo) sourceRefs() []sourceRef {
+	var refs []sourceRef
+	var walk func(path, key string, rules []Rule)
+	walk = func(path, key string, rules []Rule) {
+		for i, r := range rules {
+			rulePath := joinPath(path, indexPath(key, i))
+			if r.Source != "" {
+				refs = append(refs, sourceRef{path: rulePath, source: r.Source})
+			}
+			walk(rulePath, "match", r.Match)
+		}
+	}
+
+	var walkModifiers func(path, key string, modifiers []Modifier)
+	walkModifiers = func(path, key string, modifiers []Modifier) {
+		for i, m := range modifiers {
+			modifierPath := joinPath(path, indexPath(key, i))
+			walk(modifierPath, "rules", m.Filters)
+			walkModifiers(modifierPath, "else", m.Else)
+		}
+	}
+
+	walk("", "rules", c.Rules)
+	walkModifiers("", "modifiers", c.Modifiers)
+	return refs
 }
 
 func (c *SourceInfo) Validate() error {
+	var errs ValidationErrors
+
 	if c.Name == "" {
-		return fmt.Errorf("name is missing")
+		errs.add("name", "name is missing")
+	}
+
+	if !HasSourceType(c.Kind()) {
+		errs.add("type", "source type %q is unknown", c.Type)
+	} else {
+		for _, validate := range sourceTypeValidators(c.Kind()) {
+			errs = append(errs, validate(c)...)
+		}
+	}
+	errs = append(errs, c.validateFormat()...)
+
+	for i, rule := range c.Rules {
+		errs.nest(indexPath("rules", i), rule.Validate())
 	}
 
-	if c.Url == "" {
-		return fmt.Errorf("URL is missing")
+	for i, modifier := range c.Modifiers {
+		errs.nest(indexPath("modifiers", i), modifier.Validate())
 	}
 
-	u, err := url.Parse(c.Url)
-	if err != nil {
-		return fmt.Errorf("URL is invalid")
+	if c.Privacy != nil {
+		errs.nest("privacy", c.Privacy.Validate())
 	}
 
-	if u.Hostname() == "" {
-		return fmt.Errorf("URL is invalid (hostname)")
+	if c.Color != "" && !IsColor(c.Color) {
+		errs.add("color", "color %q is not a CSS3 color name", c.Color)
 	}
 
-	return nil
+	return errs.err()
 }
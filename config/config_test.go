@@ -2,11 +2,14 @@ package config_test
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Fesaa/ical-merger/config"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -138,3 +141,415 @@ func TestSourceInfoValidationInvalidUrl(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "URL is invalid (hostname)", err.Error())
 }
+
+func TestRegisterTwice(t *testing.T) {
+	t.Run("scoped", func(t *testing.T) {
+		config.ScopeRegistry(t)
+		config.RegisterCheck("SCOPED_CHECK")
+		config.RegisterAction("SCOPED_ACTION")
+		config.RegisterSourceType("scoped")
+
+		assert.Panics(t, func() { config.RegisterCheck("SCOPED_CHECK") })
+		assert.Panics(t, func() { config.RegisterAction("SCOPED_ACTION") })
+		assert.Panics(t, func() { config.RegisterSourceType("scoped") })
+		assert.Panics(t, func() { config.RegisterSourceType(config.SourceUrl, config.RequireUrl) })
+	})
+
+	// the names registered in the scope are gone once it ends
+	assert.False(t, config.HasCheck("SCOPED_CHECK"))
+	assert.False(t, config.HasAction("SCOPED_ACTION"))
+	assert.False(t, config.HasSourceType("scoped"))
+	assert.True(t, config.HasSourceType(config.SourceUrl))
+}
+
+func TestSourceInfoValidationUnknownCheck(t *testing.T) {
+	config.ScopeRegistry(t)
+	config.RegisterCheck("KNOWN_CHECK")
+	info := &config.SourceInfo{
+		Name: "Info",
+		Url:  "http://example.com/info",
+		Rules: []config.Rule{
+			{Check: "KNOWN_CHECK"},
+			{Check: "UNKNOWN_CHECK"},
+		},
+	}
+
+	err := info.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, `rules[1]: check "UNKNOWN_CHECK" is unknown`, err.Error())
+}
+
+func TestSourceInfoValidationUnknownAction(t *testing.T) {
+	info := &config.SourceInfo{
+		Name: "Info",
+		Url:  "http://example.com/info",
+		Modifiers: []config.Modifier{
+			{Action: "UNKNOWN_ACTION"},
+		},
+	}
+
+	err := info.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, `modifiers[0]: action "UNKNOWN_ACTION" is unknown`, err.Error())
+}
+
+func TestLoadConfigValidationErrors(t *testing.T) {
+	config.ScopeRegistry(t)
+	config.RegisterCheck("KNOWN_CHECK", config.RequireComponent, config.RequireData)
+	config.RegisterAction("KNOWN_ALARM", config.RequireModifierDuration)
+
+	tempFile, err := os.CreateTemp("", "config_test_*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	content := strings.Join([]string{
+		"sources:",
+		"- end_point: cal",
+		"  heartbeat: 0",
+		"  info:",
+		"    - name: Info",
+		"      url: http://example.com/info",
+		"      rules:",
+		"        - check: KNOWN_CHECK",
+		"          component: SUMARY",
+		"        - check: UNKNOWN_CHECK",
+		"      modifiers:",
+		"        - name: Alarm",
+		"          action: KNOWN_ALARM",
+		"          data: 60 minutes",
+	}, "\n")
+
+	if _, err := tempFile.Write([]byte(content)); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		t.Fatalf("failed to close temp file: %v", err)
+	}
+
+	_, err = config.LoadConfig(tempFile.Name())
+	assert.Error(t, err)
+
+	var errs config.ValidationErrors
+	assert.ErrorAs(t, err, &errs)
+	assert.Equal(t, []string{
+		"line 3: sources[0]: heartbeat must be greater than 0",
+		`line 9: sources[0].info[0].rules[0]: component "SUMARY" is not a valid ICS property`,
+		"line 8: sources[0].info[0].rules[0]: check KNOWN_CHECK requires data",
+		`line 10: sources[0].info[0].rules[1]: check "UNKNOWN_CHECK" is unknown`,
+		`line 14: sources[0].info[0].modifiers[0]: invalid duration "60 minutes"`,
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestParseDuration(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"-PT60M":   -time.Hour,
+		"PT15M":    15 * time.Minute,
+		"P1D":      24 * time.Hour,
+		"-P1W":     -7 * 24 * time.Hour,
+		"P1DT2H3S": 26*time.Hour + 3*time.Second,
+	} {
+		d, err := config.ParseDuration(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, d, in)
+	}
+
+	for _, in := range []string{"", "P", "PT", "1H", "PT1D", "P1H", "-PT"} {
+		_, err := config.ParseDuration(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestSourceValidationDependencies(t *testing.T) {
+	config.ScopeRegistry(t)
+	config.RegisterCheck("DEPENDENT_CHECK", config.RequireSource)
+	source := &config.Source{
+		EndPoint:  "endpoint",
+		Heartbeat: 60,
+		Info: []config.SourceInfo{
+			{
+				Name:  "a",
+				Url:   "http://example.com/a",
+				Rules: []config.Rule{{Check: "DEPENDENT_CHECK", Source: "b"}},
+			},
+			{
+				Name:  "b",
+				Url:   "http://example.com/b",
+				Rules: []config.Rule{{Check: "DEPENDENT_CHECK", Source: "a"}},
+			},
+			{
+				Name:  "c",
+				Url:   "http://example.com/c",
+				Rules: []config.Rule{{Check: "DEPENDENT_CHECK", Source: "d"}, {Check: "DEPENDENT_CHECK"}},
+			},
+		},
+	}
+
+	err := source.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		`info[0].rules[0]: circular dependency on source "b"`,
+		`info[1].rules[0]: circular dependency on source "a"`,
+		"info[2].rules[1]: check DEPENDENT_CHECK requires a source",
+		`info[2].rules[0]: depends on unknown source "d"`,
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestModifierValidationFlowAndElse(t *testing.T) {
+	config.ScopeRegistry(t)
+	config.RegisterAction("KNOWN_ACTION")
+	modifier := &config.Modifier{
+		Action: "KNOWN_ACTION",
+		Flow:   "break",
+		Else: []config.Modifier{
+			{Action: "KNOWN_ACTION", Flow: config.FlowStop},
+			{Action: "UNKNOWN_ACTION"},
+		},
+	}
+
+	err := modifier.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"flow must be continue or stop",
+		`else[1]: action "UNKNOWN_ACTION" is unknown`,
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestModifierValidationSummary(t *testing.T) {
+	config.ScopeRegistry(t)
+	config.RegisterAction("KNOWN_BLOCK")
+	modifier := &config.Modifier{Action: "KNOWN_BLOCK", Summary: "Travel to {{.Location"}
+
+	err := modifier.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, "summary is invalid: template: summary:1: unclosed action", err.Error())
+
+	modifier.Summary = "Travel to {{.Location}}"
+	assert.NoError(t, modifier.Validate())
+}
+
+func TestModifierValidationAlarm(t *testing.T) {
+	config.ScopeRegistry(t)
+	config.RegisterAction("KNOWN_EMAIL_ALARM", config.RequireModifierAlarm)
+	modifier := &config.Modifier{
+		Action: "KNOWN_EMAIL_ALARM",
+		Alarm: &config.Alarm{
+			Action:   config.AlarmEmail,
+			Trigger:  "20240304T090000Z",
+			Related:  "END",
+			Duration: "PT5M",
+		},
+	}
+
+	err := modifier.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"alarm: email alarms require attendees",
+		"alarm: email alarms require a summary",
+		"alarm: related cannot be used with an absolute trigger",
+		"alarm: repeat and duration must be set together",
+	}, strings.Split(err.Error(), "\n"))
+
+	// the email checks also apply when the trigger comes from data
+	modifier = &config.Modifier{
+		Action: "KNOWN_EMAIL_ALARM",
+		Data:   "-PT15M",
+		Alarm:  &config.Alarm{Action: config.AlarmEmail, Summary: "Reminder"},
+	}
+	err = modifier.Validate()
+	assert.Error(t, err)
+	var errs config.ValidationErrors
+	if assert.ErrorAs(t, err, &errs) && assert.Len(t, errs, 1) {
+		assert.Equal(t, "alarm", errs[0].Path)
+		assert.Equal(t, "attendees", errs[0].Field)
+	}
+}
+
+func TestCalendarMetadataValidation(t *testing.T) {
+	source := &config.Source{
+		Heartbeat: 10,
+		Calendar: config.CalendarMetadata{
+			Color:           "#ff0000",
+			RefreshInterval: "PT0M",
+			Image:           "logo.png",
+		},
+		Info: []config.SourceInfo{
+			{Name: "Info", Url: "http://example.com/info", Color: "Teal"},
+		},
+	}
+
+	err := source.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		`calendar: color "#ff0000" is not a CSS3 color name`,
+		"calendar: refresh_interval must be positive",
+		"calendar: image is invalid",
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestMappingEntriesKeepOrder(t *testing.T) {
+	var modifier config.Modifier
+	err := yaml.Unmarshal([]byte(strings.Join([]string{
+		"action: MAP",
+		"component: LOCATION",
+		"map:",
+		"  match: regex",
+		"  entries:",
+		"    '^B-': Building B",
+		"    '^A-': Building A",
+		"    '(': Invalid",
+	}, "\n")), &modifier)
+	assert.NoError(t, err)
+	assert.Equal(t, config.MapEntries{
+		{Key: "^B-", Value: "Building B"},
+		{Key: "^A-", Value: "Building A"},
+		{Key: "(", Value: "Invalid"},
+	}, modifier.Map.Entries)
+
+	err = modifier.Map.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `key "(" is invalid`)
+}
+
+func TestSourceValidationEndpointRules(t *testing.T) {
+	config.ScopeRegistry(t)
+	config.RegisterCheck("ENDPOINT_CHECK", config.RequireSource)
+	config.RegisterAction("ENDPOINT_ACTION")
+	source := &config.Source{
+		EndPoint:  "endpoint",
+		Heartbeat: 60,
+		Info:      []config.SourceInfo{{Name: "a", Url: "http://example.com/a"}},
+		Rules:     []config.Rule{{Check: "ENDPOINT_CHECK", Source: "a"}, {Check: "UNKNOWN_CHECK"}},
+		Modifiers: []config.Modifier{{
+			Action:  "ENDPOINT_ACTION",
+			Filters: []config.Rule{{Check: "ENDPOINT_CHECK", Source: "b"}},
+		}},
+	}
+
+	err := source.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		`rules[1]: check "UNKNOWN_CHECK" is unknown`,
+		`modifiers[0].rules[0]: depends on unknown source "b"`,
+	}, strings.Split(err.Error(), "\n"))
+
+	// the error points at the field naming the source
+	var errs config.ValidationErrors
+	if assert.ErrorAs(t, err, &errs) {
+		assert.Equal(t, "source", errs[1].Field)
+	}
+}
+
+func TestSourceInfoValidationSourceTypes(t *testing.T) {
+	info := &config.SourceInfo{Name: "Info", Type: "unknown"}
+	err := info.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, `source type "unknown" is unknown`, err.Error())
+
+	config.ScopeRegistry(t)
+	config.RegisterSourceType(config.SourceStatic, config.RequireEvents)
+	info = &config.SourceInfo{Name: "Info", Type: config.SourceStatic, Events: []config.StaticEvent{
+		{Summary: "Closed", Start: "2024-12-24", End: "2024-12-26"},
+		{Start: "2024-12-24", End: "2024-12-26T10:00", RRule: "BYDAY=MO"},
+	}}
+	err = info.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"events[1]: summary is missing",
+		"events[1]: start and end must both be dates or both be times",
+		"events[1]: rrule requires FREQ to be one of SECONDLY, MINUTELY, HOURLY, DAILY, WEEKLY, MONTHLY, YEARLY",
+	}, strings.Split(err.Error(), "\n"))
+
+	config.RegisterSourceType(config.SourceTerms, config.RequireTerms)
+	info = &config.SourceInfo{Name: "Info", Type: config.SourceTerms, Terms: []config.Term{
+		{Name: "Term 1", Start: "2024-09-02", End: "2024-08-01"},
+	}, Weeks: &config.Weeks{Summary: "{{.Week"}}
+	err = info.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"terms[0]: end is before start",
+		`weeks: summary is invalid: template: summary:1: unclosed action`,
+	}, strings.Split(err.Error(), "\n"))
+
+	config.RegisterSourceType(config.SourceVCard, config.RequireVCard)
+	info = &config.SourceInfo{Name: "Info", Type: config.SourceVCard, VCard: &config.VCard{
+		Path:    filepath.Join(t.TempDir(), "missing.vcf"),
+		Summary: "{{.Name",
+	}}
+	err = info.Validate()
+	assert.Error(t, err)
+	errs := strings.Split(err.Error(), "\n")
+	assert.Len(t, errs, 2)
+	assert.True(t, strings.HasPrefix(errs[0], "vcard: path is not readable: "), errs[0])
+	assert.Equal(t, "vcard: summary is invalid: template: summary:1: unclosed action", errs[1])
+
+	config.RegisterSourceType(config.SourceMail, config.RequireMail)
+	info = &config.SourceInfo{Name: "Info", Type: config.SourceMail}
+	err = info.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, "source type mail requires a path", err.Error())
+
+	info.Mail = &config.Mail{Path: t.TempDir()}
+	assert.NoError(t, info.Validate())
+
+	config.RegisterSourceType(config.SourceExec, config.RequireExec)
+	info = &config.SourceInfo{Name: "Info", Type: config.SourceExec, Format: config.FormatJCal, Exec: &config.Exec{
+		Command: "scrape",
+		Dir:     filepath.Join(t.TempDir(), "missing"),
+		Timeout: "-PT1M",
+	}}
+	err = info.Validate()
+	assert.Error(t, err)
+	errs = strings.Split(err.Error(), "\n")
+	assert.Len(t, errs, 2)
+	assert.True(t, strings.HasPrefix(errs[0], "exec: dir is not readable: "), errs[0])
+	assert.Equal(t, "exec: timeout must be positive", errs[1])
+}
+
+func TestSourceInfoValidationFormat(t *testing.T) {
+	info := &config.SourceInfo{Name: "Info", Url: "https://example.com/feed", Format: "yaml"}
+	err := info.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, `format "yaml" is unknown`, err.Error())
+
+	info = &config.SourceInfo{Name: "Info", Url: "https://example.com/feed.csv", Format: config.FormatCSV, Csv: &config.Csv{
+		Delimiter: "||",
+		Columns:   config.FieldMapping{Start: "start", End: "end", Duration: "length"},
+	}}
+	err = info.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"csv: delimiter must be a single character",
+		"csv.columns: summary is missing",
+		"csv.columns: end and duration cannot be used together",
+	}, strings.Split(err.Error(), "\n"))
+
+	info = &config.SourceInfo{Name: "Info", Url: "https://example.com/feed.json", Format: config.FormatJSON}
+	err = info.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, "format json requires fields", err.Error())
+
+	info = &config.SourceInfo{Name: "Info", Url: "https://example.com/feed.json", Format: config.FormatJCal}
+	assert.NoError(t, info.Validate())
+}
+
+func TestModifierValidationHook(t *testing.T) {
+	config.ScopeRegistry(t)
+	config.RegisterAction(config.HOOK, config.RequireModifierHook)
+	modifier := &config.Modifier{Action: config.HOOK}
+	err := modifier.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, "action HOOK requires a hook", err.Error())
+
+	modifier.Hook = &config.Exec{Args: []string{"clean.py"}, Timeout: "soon"}
+	err = modifier.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"hook: command is missing",
+		`hook: invalid duration "soon"`,
+	}, strings.Split(err.Error(), "\n"))
+
+	modifier.Hook = &config.Exec{Command: "python3", Args: []string{"clean.py"}, Timeout: "PT10S"}
+	assert.NoError(t, modifier.Validate())
+}
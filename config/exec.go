@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// DefaultExecTimeout is used when a command does not set a timeout
+const DefaultExecTimeout = time.Minute
+
+// Exec configures a command run by an exec source or a hook. The command is run without a shell,
+// Env is added to the environment of the merger and Timeout is an ICS duration.
+type Exec struct {
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty"`
+	Dir     string            `yaml:"dir,omitempty"`
+	Timeout string            `yaml:"timeout,omitempty"`
+}
+
+func (e *Exec) Validate() error {
+	var errs ValidationErrors
+
+	if e.Command == "" {
+		errs.add("command", "command is missing")
+	}
+	if e.Dir != "" {
+		if info, err := os.Stat(e.Dir); err != nil {
+			errs.add("dir", "dir is not readable: %s", err)
+		} else if !info.IsDir() {
+			errs.add("dir", "dir is not a directory")
+		}
+	}
+	if e.Timeout != "" {
+		if d, err := ParseDuration(e.Timeout); err != nil {
+			errs.add("timeout", "%s", err)
+		} else if d <= 0 {
+			errs.add("timeout", "timeout must be positive")
+		}
+	}
+
+	return errs.err()
+}
+
+// TimeoutOf returns the timeout of the command, defaulting to DefaultExecTimeout
+func (e *Exec) TimeoutOf() time.Duration {
+	if d, err := ParseDuration(e.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultExecTimeout
+}
+
+// RequireExec ensures an exec source has a command to run
+func RequireExec(s *SourceInfo) ValidationErrors {
+	if s.Exec == nil || s.Exec.Command == "" {
+		return FieldError("exec", "source type %s requires a command", s.Kind())
+	}
+
+	var errs ValidationErrors
+	errs.nest("exec", s.Exec.Validate())
+	return errs
+}
+
+// RequireModifierHook ensures the modifier has a hook to run, the hook itself is validated
+// with the modifier
+func RequireModifierHook(m *Modifier) ValidationErrors {
+	if m.Hook == nil {
+		return FieldError("hook", "action %s requires a hook", m.Action)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"maps"
+	"testing"
+)
+
+// ScopeRegistry gives the test its own copy of the registered checks, actions and source
+// types, so it can register names without affecting other tests
+func ScopeRegistry(t testing.TB) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	prevChecks, prevActions, prevSourceTypes := checks, actions, sourceTypes
+	checks, actions, sourceTypes = maps.Clone(checks), maps.Clone(actions), maps.Clone(sourceTypes)
+	t.Cleanup(func() {
+		registryLock.Lock()
+		defer registryLock.Unlock()
+		checks, actions, sourceTypes = prevChecks, prevActions, prevSourceTypes
+	})
+}
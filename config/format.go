@@ -0,0 +1,111 @@
+package config
+
+import (
+	"slices"
+	"unicode/utf8"
+)
+
+// Format is the format of the calendar loaded by a url or exec source
+type Format string
+
+const (
+	// FormatICS is iCalendar (RFC 5545), the default
+	FormatICS Format = "ics"
+	// FormatJCal is iCalendar as JSON (RFC 7265)
+	FormatJCal Format = "jcal"
+	// FormatXCal is iCalendar as XML (RFC 6321)
+	FormatXCal Format = "xcal"
+	// FormatCSV is a CSV file with a header row, one event per row
+	FormatCSV Format = "csv"
+	// FormatJSON is a JSON document holding an array of events
+	FormatJSON Format = "json"
+)
+
+var formats = []Format{FormatICS, FormatJCal, FormatXCal, FormatCSV, FormatJSON}
+
+// FieldMapping maps the fields of an event to CSV columns or JSON paths. The values are read
+// like the fields of a StaticEvent, times may use TimeFormat instead.
+type FieldMapping struct {
+	Uid         string `yaml:"uid,omitempty"`
+	Summary     string `yaml:"summary"`
+	Description string `yaml:"description,omitempty"`
+	Location    string `yaml:"location,omitempty"`
+	Start       string `yaml:"start"`
+	End         string `yaml:"end,omitempty"`
+	Duration    string `yaml:"duration,omitempty"`
+	RRule       string `yaml:"rrule,omitempty"`
+	// TimeFormat is a Go time layout used for start and end when they are not ISO 8601
+	TimeFormat string `yaml:"time_format,omitempty"`
+}
+
+func (f *FieldMapping) Validate() error {
+	var errs ValidationErrors
+
+	if f.Summary == "" {
+		errs.add("summary", "summary is missing")
+	}
+	if f.Start == "" {
+		errs.add("start", "start is missing")
+	}
+	if f.End != "" && f.Duration != "" {
+		errs.add("end", "end and duration cannot be used together")
+	}
+
+	return errs.err()
+}
+
+// Csv configures a source in the csv format, columns are referred to by their header
+type Csv struct {
+	// Delimiter defaults to a comma
+	Delimiter string       `yaml:"delimiter,omitempty"`
+	Columns   FieldMapping `yaml:"columns"`
+}
+
+// Json configures a source in the json format. Events is the path to the array of events,
+// such as $.data.items, the fields are paths relative to an event.
+type Json struct {
+	Events string       `yaml:"events,omitempty"`
+	Fields FieldMapping `yaml:"fields"`
+}
+
+// FormatOf returns the format of the source, defaulting to ics
+func (c *SourceInfo) FormatOf() Format {
+	if c.Format == "" {
+		return FormatICS
+	}
+	return c.Format
+}
+
+// validateFormat ensures the format is known and has the mapping it needs
+func (c *SourceInfo) validateFormat() ValidationErrors {
+	var errs ValidationErrors
+	if c.Format == "" {
+		return nil
+	}
+	if !slices.Contains(formats, c.Format) {
+		errs.add("format", "format %q is unknown", c.Format)
+		return errs
+	}
+	if c.Kind() != SourceUrl && c.Kind() != SourceExec {
+		errs.add("format", "format requires a url or exec source")
+	}
+
+	switch c.Format {
+	case FormatCSV:
+		if c.Csv == nil {
+			errs.add("csv", "format %s requires columns", c.Format)
+			break
+		}
+		if c.Csv.Delimiter != "" && utf8.RuneCountInString(c.Csv.Delimiter) != 1 {
+			errs.nest("csv", FieldError("delimiter", "delimiter must be a single character"))
+		}
+		errs.nest("csv.columns", c.Csv.Columns.Validate())
+	case FormatJSON:
+		if c.Json == nil {
+			errs.add("json", "format %s requires fields", c.Format)
+			break
+		}
+		errs.nest("json.fields", c.Json.Fields.Validate())
+	}
+	return errs
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Geo describes the area location based checks test against. Coordinates are written
+// as "lat,lon", the box as "south,west,north,east".
+type Geo struct {
+	Point  string  `yaml:"point,omitempty"`
+	Radius float64 `yaml:"radius,omitempty"` // kilometres
+	Box    string  `yaml:"box,omitempty"`
+	// Gazetteer is a local file mapping place names to coordinates, used for events
+	// without a GEO property
+	Gazetteer string `yaml:"gazetteer,omitempty"`
+}
+
+func (g *Geo) Validate() error {
+	var errs ValidationErrors
+
+	if g.Point != "" {
+		if _, _, err := ParseCoordinates(g.Point); err != nil {
+			errs.add("point", "%s", err)
+		}
+	}
+
+	if g.Radius < 0 {
+		errs.add("radius", "radius must not be negative")
+	}
+
+	if g.Box != "" {
+		if _, err := ParseBox(g.Box); err != nil {
+			errs.add("box", "%s", err)
+		}
+	}
+
+	if g.Gazetteer != "" {
+		if _, err := os.Stat(g.Gazetteer); err != nil {
+			errs.add("gazetteer", "gazetteer is not readable: %s", err)
+		}
+	}
+
+	return errs.err()
+}
+
+// ParseCoordinates parses "lat,lon" or the "lat;lon" form used by the GEO property
+func ParseCoordinates(s string) (float64, float64, error) {
+	values, err := parseFloats(s, 2)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lat, lon := values[0], values[1]
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("coordinates %q are out of range", s)
+	}
+	return lat, lon, nil
+}
+
+// ParseBox parses "south,west,north,east"
+func ParseBox(s string) ([4]float64, error) {
+	values, err := parseFloats(s, 4)
+	if err != nil {
+		return [4]float64{}, err
+	}
+
+	box := [4]float64{values[0], values[1], values[2], values[3]}
+	if box[0] > box[2] {
+		return [4]float64{}, fmt.Errorf("box %q has its south edge above its north edge", s)
+	}
+	return box, nil
+}
+
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' })
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected %d numbers, got %q", n, s)
+	}
+
+	values := make([]float64, n)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", part)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// RequireGeoRadius ensures the rule has a point and a radius
+func RequireGeoRadius(r *Rule) ValidationErrors {
+	if r.Geo == nil || r.Geo.Point == "" {
+		return FieldError("geo", "check %s requires geo.point", r.Check)
+	}
+	if r.Geo.Radius <= 0 {
+		return FieldError("geo", "check %s requires a positive geo.radius", r.Check)
+	}
+	return nil
+}
+
+// RequireGeoBox ensures the rule has a bounding box
+func RequireGeoBox(r *Rule) ValidationErrors {
+	if r.Geo == nil || r.Geo.Box == "" {
+		return FieldError("geo", "check %s requires geo.box", r.Check)
+	}
+	return nil
+}
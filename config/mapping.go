@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MapMatch decides how the keys of a mapping are compared to the value of a property
+type MapMatch string
+
+const (
+	// MapExact maps values equal to the key
+	MapExact MapMatch = "exact"
+	// MapPrefix maps values starting with the key to the value of the longest key
+	MapPrefix MapMatch = "prefix"
+	// MapRegex maps values matching the key, the first key wins. The mapped value may
+	// refer to capture groups as $1 or ${name}
+	MapRegex MapMatch = "regex"
+)
+
+type MapEntry struct {
+	Key   string
+	Value string
+}
+
+// MapEntries is a mapping which keeps the order it was written in
+type MapEntries []MapEntry
+
+func (m *MapEntries) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: entries must be a mapping", node.Line)
+	}
+	entries := make(MapEntries, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var entry MapEntry
+		if err := node.Content[i].Decode(&entry.Key); err != nil {
+			return err
+		}
+		if err := node.Content[i+1].Decode(&entry.Value); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+	*m = entries
+	return nil
+}
+
+// Mapping is the lookup table of the MAP action. The inline entries come before those
+// from the file, a CSV file with key,value lines or a YAML or JSON mapping.
+type Mapping struct {
+	File    string     `yaml:"file,omitempty"`
+	Entries MapEntries `yaml:"entries,omitempty"`
+	Match   MapMatch   `yaml:"match,omitempty"`
+	// Fallback replaces values no key matches, they are kept when empty
+	Fallback string `yaml:"fallback,omitempty"`
+}
+
+func (m *Mapping) Validate() error {
+	var errs ValidationErrors
+
+	switch m.Match {
+	case "", MapExact, MapPrefix, MapRegex:
+	default:
+		errs.add("match", "match must be one of %s, %s or %s", MapExact, MapPrefix, MapRegex)
+	}
+
+	if m.File != "" {
+		if _, err := os.Stat(m.File); err != nil {
+			errs.add("file", "file is not readable: %s", err)
+		}
+		switch strings.ToLower(path.Ext(m.File)) {
+		case ".csv", ".yaml", ".yml", ".json":
+		default:
+			errs.add("file", "file must be a .csv, .yaml or .json file")
+		}
+	}
+
+	if m.Match == MapRegex {
+		for _, entry := range m.Entries {
+			if _, err := regexp.Compile(entry.Key); err != nil {
+				errs.add("entries", "key %q is invalid: %s", entry.Key, err)
+			}
+		}
+	}
+
+	return errs.err()
+}
+
+// RequireModifierMap ensures the modifier has a mapping to look values up in
+func RequireModifierMap(m *Modifier) ValidationErrors {
+	if m.Map == nil || (m.Map.File == "" && len(m.Map.Entries) == 0) {
+		return FieldError("map", "action %s requires a map with a file or entries", m.Action)
+	}
+	return nil
+}
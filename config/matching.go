@@ -0,0 +1,27 @@
+package config
+
+import (
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization is the Unicode normalization form applied before matching
+type Normalization string
+
+const (
+	NFC  Normalization = "NFC"
+	NFKC Normalization = "NFKC"
+)
+
+// stripAccents removes diacritics by decomposing s and dropping the combining marks
+func stripAccents(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	out, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// CalendarMetadata holds the RFC 7986 properties of an endpoint
+type CalendarMetadata struct {
+	// Name defaults to the xwr_name of the endpoint
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Color       string `yaml:"color,omitempty"`
+	// RefreshInterval is a duration, defaults to the heartbeat of the endpoint
+	RefreshInterval string `yaml:"refresh_interval,omitempty"`
+	Image           string `yaml:"image,omitempty"`
+}
+
+func (m *CalendarMetadata) Validate() error {
+	var errs ValidationErrors
+
+	if m.Color != "" && !IsColor(m.Color) {
+		errs.add("color", "color %q is not a CSS3 color name", m.Color)
+	}
+
+	if m.RefreshInterval != "" {
+		if d, err := ParseDuration(m.RefreshInterval); err != nil {
+			errs.add("refresh_interval", "%s", err)
+		} else if d <= 0 {
+			errs.add("refresh_interval", "refresh_interval must be positive")
+		}
+	}
+
+	if m.Image != "" {
+		if u, err := url.Parse(m.Image); err != nil || u.Hostname() == "" {
+			errs.add("image", "image is invalid")
+		}
+	}
+
+	return errs.err()
+}
+
+// colors holds the CSS3 color names, the values RFC 7986 allows for COLOR
+var colors = map[string]struct{}{
+	"aliceblue": {}, "antiquewhite": {}, "aqua": {}, "aquamarine": {}, "azure": {}, "beige": {},
+	"bisque": {}, "black": {}, "blanchedalmond": {}, "blue": {}, "blueviolet": {}, "brown": {},
+	"burlywood": {}, "cadetblue": {}, "chartreuse": {}, "chocolate": {}, "coral": {},
+	"cornflowerblue": {}, "cornsilk": {}, "crimson": {}, "cyan": {}, "darkblue": {}, "darkcyan": {},
+	"darkgoldenrod": {}, "darkgray": {}, "darkgreen": {}, "darkgrey": {}, "darkkhaki": {},
+	"darkmagenta": {}, "darkolivegreen": {}, "darkorange": {}, "darkorchid": {}, "darkred": {},
+	"darksalmon": {}, "darkseagreen": {}, "darkslateblue": {}, "darkslategray": {},
+	"darkslategrey": {}, "darkturquoise": {}, "darkviolet": {}, "deeppink": {}, "deepskyblue": {},
+	"dimgray": {}, "dimgrey": {}, "dodgerblue": {}, "firebrick": {}, "floralwhite": {},
+	"forestgreen": {}, "fuchsia": {}, "gainsboro": {}, "ghostwhite": {}, "gold": {}, "goldenrod": {},
+	"gray": {}, "green": {}, "greenyellow": {}, "grey": {}, "honeydew": {}, "hotpink": {},
+	"indianred": {}, "indigo": {}, "ivory": {}, "khaki": {}, "lavender": {}, "lavenderblush": {},
+	"lawngreen": {}, "lemonchiffon": {}, "lightblue": {}, "lightcoral": {}, "lightcyan": {},
+	"lightgoldenrodyellow": {}, "lightgray": {}, "lightgreen": {}, "lightgrey": {}, "lightpink": {},
+	"lightsalmon": {}, "lightseagreen": {}, "lightskyblue": {}, "lightslategray": {},
+	"lightslategrey": {}, "lightsteelblue": {}, "lightyellow": {}, "lime": {}, "limegreen": {},
+	"linen": {}, "magenta": {}, "maroon": {}, "mediumaquamarine": {}, "mediumblue": {},
+	"mediumorchid": {}, "mediumpurple": {}, "mediumseagreen": {}, "mediumslateblue": {},
+	"mediumspringgreen": {}, "mediumturquoise": {}, "mediumvioletred": {}, "midnightblue": {},
+	"mintcream": {}, "mistyrose": {}, "moccasin": {}, "navajowhite": {}, "navy": {}, "oldlace": {},
+	"olive": {}, "olivedrab": {}, "orange": {}, "orangered": {}, "orchid": {}, "palegoldenrod": {},
+	"palegreen": {}, "paleturquoise": {}, "palevioletred": {}, "papayawhip": {}, "peachpuff": {},
+	"peru": {}, "pink": {}, "plum": {}, "powderblue": {}, "purple": {}, "red": {}, "rosybrown": {},
+	"royalblue": {}, "saddlebrown": {}, "salmon": {}, "sandybrown": {}, "seagreen": {},
+	"seashell": {}, "sienna": {}, "silver": {}, "skyblue": {}, "slateblue": {}, "slategray": {},
+	"slategrey": {}, "snow": {}, "springgreen": {}, "steelblue": {}, "tan": {}, "teal": {},
+	"thistle": {}, "tomato": {}, "turquoise": {}, "violet": {}, "wheat": {}, "white": {},
+	"whitesmoke": {}, "yellow": {}, "yellowgreen": {},
+}
+
+// IsColor reports whether name is a CSS3 color name
+func IsColor(name string) bool {
+	_, ok := colors[strings.ToLower(name)]
+	return ok
+}
@@ -0,0 +1,79 @@
+package config
+
+// PrivacyLevel decides how much of an event is published
+type PrivacyLevel string
+
+const (
+	// PrivacyFull publishes events as they are
+	PrivacyFull PrivacyLevel = "full"
+	// PrivacyTitle keeps the summary and times, but removes all other details
+	PrivacyTitle PrivacyLevel = "title"
+	// PrivacyBusy replaces the summary by a label and removes all other details
+	PrivacyBusy PrivacyLevel = "busy"
+	// PrivacyHidden removes the events
+	PrivacyHidden PrivacyLevel = "hidden"
+)
+
+var privacyLevels = []PrivacyLevel{PrivacyFull, PrivacyTitle, PrivacyBusy, PrivacyHidden}
+
+// DefaultBusyLabel is used as summary of busy events when no label is configured
+const DefaultBusyLabel = "Busy"
+
+// Privacy configures the privacy level of an endpoint or source. The fields set on a source
+// take precedence over those of its endpoint.
+type Privacy struct {
+	Level PrivacyLevel `yaml:"level,omitempty"`
+	// Label replaces the summary of busy events
+	Label string `yaml:"label,omitempty"`
+	// Private is the minimum level of events marked CLASS:PRIVATE or CLASS:CONFIDENTIAL,
+	// defaults to busy
+	Private PrivacyLevel `yaml:"private,omitempty"`
+}
+
+func (p *Privacy) Validate() error {
+	var errs ValidationErrors
+
+	if p.Level != "" && p.Level.rank() < 0 {
+		errs.add("level", "level must be one of %s, %s, %s or %s", PrivacyFull, PrivacyTitle, PrivacyBusy, PrivacyHidden)
+	}
+	if p.Private != "" && p.Private.rank() < 0 {
+		errs.add("private", "private must be one of %s, %s, %s or %s", PrivacyFull, PrivacyTitle, PrivacyBusy, PrivacyHidden)
+	}
+
+	return errs.err()
+}
+
+// Inherit returns the privacy with the fields left empty taken from parent, the privacy of
+// the endpoint of a source
+func (p Privacy) Inherit(parent Privacy) Privacy {
+	if p.Level == "" {
+		p.Level = parent.Level
+	}
+	if p.Label == "" {
+		p.Label = parent.Label
+	}
+	if p.Private == "" {
+		p.Private = parent.Private
+	}
+	return p
+}
+
+// Stricter returns the level publishing the least of l and other
+func (l PrivacyLevel) Stricter(other PrivacyLevel) PrivacyLevel {
+	if other.rank() > l.rank() {
+		return other
+	}
+	return l
+}
+
+func (l PrivacyLevel) rank() int {
+	if l == "" {
+		return 0
+	}
+	for i, level := range privacyLevels {
+		if level == l {
+			return i
+		}
+	}
+	return -1
+}
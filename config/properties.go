@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// properties holds every property name defined for events by RFC 5545 and RFC 7986
+var properties = map[string]struct{}{
+	"ACTION": {}, "ATTACH": {}, "ATTENDEE": {}, "CATEGORIES": {}, "CLASS": {}, "COLOR": {},
+	"COMMENT": {}, "CONFERENCE": {}, "CONTACT": {}, "CREATED": {}, "DESCRIPTION": {},
+	"DTEND": {}, "DTSTAMP": {}, "DTSTART": {}, "DURATION": {}, "EXDATE": {}, "EXRULE": {},
+	"GEO": {}, "IMAGE": {}, "LAST-MODIFIED": {}, "LOCATION": {}, "ORGANIZER": {},
+	"PRIORITY": {}, "RDATE": {}, "RECURRENCE-ID": {}, "RELATED-TO": {}, "REPEAT": {},
+	"REQUEST-STATUS": {}, "RESOURCES": {}, "RRULE": {}, "SEQUENCE": {}, "STATUS": {},
+	"SUMMARY": {}, "TRANSP": {}, "TRIGGER": {}, "UID": {}, "URL": {},
+}
+
+// IsProperty reports whether name is a valid ICS property name, experimental
+// X- properties are accepted as well
+func IsProperty(name string) bool {
+	if strings.HasPrefix(name, "X-") && len(name) > 2 {
+		return true
+	}
+	_, ok := properties[name]
+	return ok
+}
+
+// ParseDuration parses an RFC 5545 duration such as -PT15M, P1D or P1W
+func ParseDuration(s string) (time.Duration, error) {
+	in := s
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign = -1
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", in)
+	}
+	s = s[1:]
+
+	var (
+		d      time.Duration
+		inTime bool
+		num    int
+		digits bool
+	)
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			num = num*10 + int(r-'0')
+			digits = true
+			continue
+		case r == 'T' && !inTime && !digits:
+			inTime = true
+			continue
+		}
+
+		if !digits {
+			return 0, fmt.Errorf("invalid duration %q", in)
+		}
+
+		var unit time.Duration
+		switch {
+		case r == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case r == 'H' && inTime:
+			unit = time.Hour
+		case r == 'M' && inTime:
+			unit = time.Minute
+		case r == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", in)
+		}
+		d += time.Duration(num) * unit
+		num = 0
+		digits = false
+	}
+
+	if digits || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid duration %q", in)
+	}
+	return sign * d, nil
+}
+
+// TimeRange is a range of wall clock times within a day, End is exclusive
+type TimeRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseTimeRange parses a range such as 09:00-17:30
+func ParseTimeRange(s string) (TimeRange, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return TimeRange{}, fmt.Errorf("invalid time range %q", s)
+	}
+
+	start, err := parseClock(strings.TrimSpace(from))
+	if err != nil {
+		return TimeRange{}, fmt.Errorf("invalid time range %q", s)
+	}
+	end, err := parseClock(strings.TrimSpace(to))
+	if err != nil {
+		return TimeRange{}, fmt.Errorf("invalid time range %q", s)
+	}
+	if end <= start {
+		return TimeRange{}, fmt.Errorf("time range %q ends before it starts", s)
+	}
+	return TimeRange{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"text/template"
+)
+
+// The config package only knows the names of the available checks, actions and source
+// types, the implementations are registered by the ical package. This allows validation
+// to reject unknown names without importing ical. URL sources are always known.
+var (
+	registryLock sync.RWMutex
+	checks       = map[string][]RuleValidator{}
+	actions      = map[Action][]ModifierValidator{}
+	sourceTypes  = map[SourceType][]SourceValidator{SourceUrl: {RequireUrl}}
+)
+
+// RuleValidator validates the fields a specific check depends on
+type RuleValidator func(r *Rule) ValidationErrors
+
+// ModifierValidator validates the fields a specific action depends on
+type ModifierValidator func(m *Modifier) ValidationErrors
+
+// SourceValidator validates the fields a specific source type depends on
+type SourceValidator func(s *SourceInfo) ValidationErrors
+
+// RegisterCheck marks name as a known rule check, validators are run for every rule using it.
+// It panics if the name is already taken.
+func RegisterCheck(name string, validators ...RuleValidator) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, ok := checks[name]; ok {
+		panic(fmt.Sprintf("config: RegisterCheck called twice for %s", name))
+	}
+	checks[name] = validators
+}
+
+// RegisterAction marks action as a known modifier action, validators are run for every
+// modifier using it. It panics if the action is already taken.
+func RegisterAction(action Action, validators ...ModifierValidator) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, ok := actions[action]; ok {
+		panic(fmt.Sprintf("config: RegisterAction called twice for %s", action))
+	}
+	actions[action] = validators
+}
+
+// RegisterSourceType marks t as a known source type, validators are run for every
+// source of that type. It panics if the type is already taken.
+func RegisterSourceType(t SourceType, validators ...SourceValidator) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, ok := sourceTypes[t]; ok {
+		panic(fmt.Sprintf("config: RegisterSourceType called twice for %s", t))
+	}
+	sourceTypes[t] = validators
+}
+
+// HasCheck reports whether a check with the given name has been registered
+func HasCheck(name string) bool {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	_, ok := checks[name]
+	return ok
+}
+
+// HasAction reports whether the given action has been registered
+func HasAction(action Action) bool {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	_, ok := actions[action]
+	return ok
+}
+
+// HasSourceType reports whether the given source type has been registered
+func HasSourceType(t SourceType) bool {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	_, ok := sourceTypes[t]
+	return ok
+}
+
+func checkValidators(name string) []RuleValidator {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return checks[name]
+}
+
+func actionValidators(action Action) []ModifierValidator {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return actions[action]
+}
+
+func sourceTypeValidators(t SourceType) []SourceValidator {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return sourceTypes[t]
+}
+
+// RequireComponent ensures the rule points to a valid ICS property
+func RequireComponent(r *Rule) ValidationErrors {
+	return validateComponent(r.Component)
+}
+
+// RequireData ensures the rule has data to compare against
+func RequireData(r *Rule) ValidationErrors {
+	if len(r.Data) == 0 && r.DataFile == "" && r.DataUrl == "" {
+		return FieldError("data", "check %s requires data", r.Check)
+	}
+	return nil
+}
+
+// RequireSource ensures the rule points to another source
+func RequireSource(r *Rule) ValidationErrors {
+	if r.Source == "" {
+		return FieldError("source", "check %s requires a source", r.Check)
+	}
+	return nil
+}
+
+// RequireModifierComponent ensures the modifier points to a valid ICS property
+func RequireModifierComponent(m *Modifier) ValidationErrors {
+	return validateComponent(m.Component)
+}
+
+// RequireModifierData ensures the modifier has non-empty data
+func RequireModifierData(m *Modifier) ValidationErrors {
+	if m.Data == "" {
+		return FieldError("data", "action %s requires data", m.Action)
+	}
+	return nil
+}
+
+// RequireModifierDuration ensures the modifier data is a valid ICS duration
+func RequireModifierDuration(m *Modifier) ValidationErrors {
+	if m.Data == "" {
+		return FieldError("data", "action %s requires a duration", m.Action)
+	}
+	if _, err := ParseDuration(m.Data); err != nil {
+		return FieldError("data", "%s", err)
+	}
+	return nil
+}
+
+// OptionalModifierDuration ensures the modifier data is empty or a non-negative ICS duration
+func OptionalModifierDuration(m *Modifier) ValidationErrors {
+	if m.Data == "" {
+		return nil
+	}
+	if d, err := ParseDuration(m.Data); err != nil {
+		return FieldError("data", "%s", err)
+	} else if d < 0 {
+		return FieldError("data", "action %s requires a non-negative duration", m.Action)
+	}
+	return nil
+}
+
+// RequireModifierPattern ensures the modifier has a valid regular expression
+func RequireModifierPattern(m *Modifier) ValidationErrors {
+	if m.Pattern == "" {
+		return FieldError("pattern", "action %s requires a pattern", m.Action)
+	}
+	if _, err := regexp.Compile(m.Pattern); err != nil {
+		return FieldError("pattern", "pattern is invalid: %s", err)
+	}
+	return nil
+}
+
+// RequireModifierTemplate ensures the modifier data is a valid text/template
+func RequireModifierTemplate(m *Modifier) ValidationErrors {
+	if m.Data == "" {
+		return FieldError("data", "action %s requires a template", m.Action)
+	}
+	if _, err := template.New(m.Name).Parse(m.Data); err != nil {
+		return FieldError("data", "template is invalid: %s", err)
+	}
+	return nil
+}
+
+// RequireModifierPositiveDuration ensures the modifier data is a positive ICS duration
+func RequireModifierPositiveDuration(m *Modifier) ValidationErrors {
+	if errs := RequireModifierDuration(m); errs != nil {
+		return errs
+	}
+	if d, _ := ParseDuration(m.Data); d <= 0 {
+		return FieldError("data", "action %s requires a positive duration", m.Action)
+	}
+	return nil
+}
+
+// RequireModifierTimeRange ensures the modifier data is a time range such as 09:00-17:00
+func RequireModifierTimeRange(m *Modifier) ValidationErrors {
+	if m.Data == "" {
+		return FieldError("data", "action %s requires a time range", m.Action)
+	}
+	if _, err := ParseTimeRange(m.Data); err != nil {
+		return FieldError("data", "%s", err)
+	}
+	return nil
+}
+
+func validateComponent(component string) ValidationErrors {
+	if component == "" {
+		return FieldError("component", "component is missing")
+	}
+	if !IsProperty(component) {
+		return FieldError("component", "component %q is not a valid ICS property", component)
+	}
+	return nil
+}
+
+// Checks returns the sorted names of all registered checks
+func Checks() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Actions returns the sorted names of all registered actions
+func Actions() []Action {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	names := make([]Action, 0, len(actions))
+	for action := range actions {
+		names = append(names, action)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
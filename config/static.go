@@ -0,0 +1,304 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SourceType decides where the events of a source come from
+type SourceType string
+
+const (
+	// SourceUrl loads an ICS calendar from Url, the default
+	SourceUrl SourceType = "url"
+	// SourceStatic uses the events defined in Events
+	SourceStatic SourceType = "static"
+	// SourceWeeks generates a marker on the first day of every week
+	SourceWeeks SourceType = "weeks"
+	// SourceTerms generates an event for every term in Terms
+	SourceTerms SourceType = "terms"
+	// SourceHolidays computes the public holidays in Holidays
+	SourceHolidays SourceType = "holidays"
+	// SourceVCard generates birthdays and anniversaries from the vCards in VCard
+	SourceVCard SourceType = "vcard"
+	// SourceMail extracts the invitations from the mailbox in Mail
+	SourceMail SourceType = "mail"
+	// SourceExec runs the command in Exec and reads the calendar from its output
+	SourceExec SourceType = "exec"
+)
+
+// StaticEvent is an event defined in the config. Times are written as 2024-03-04 for all-day
+// events, 2024-03-04T09:00 in the local time zone or 2024-03-04T09:00:00Z.
+type StaticEvent struct {
+	// Uid defaults to one derived from the source name, summary and start
+	Uid         string `yaml:"uid,omitempty"`
+	Summary     string `yaml:"summary"`
+	Description string `yaml:"description,omitempty"`
+	Location    string `yaml:"location,omitempty"`
+	Start       string `yaml:"start"`
+	End         string `yaml:"end,omitempty"`
+	Duration    string `yaml:"duration,omitempty"`
+	RRule       string `yaml:"rrule,omitempty"`
+}
+
+func (e *StaticEvent) Validate() error {
+	var errs ValidationErrors
+
+	if e.Summary == "" {
+		errs.add("summary", "summary is missing")
+	}
+
+	start, allDay, err := ParseEventTime(e.Start)
+	if err != nil {
+		errs.add("start", "%s", err)
+	}
+
+	if e.End != "" {
+		end, endAllDay, err := ParseEventTime(e.End)
+		switch {
+		case err != nil:
+			errs.add("end", "%s", err)
+		case e.Duration != "":
+			errs.add("end", "end and duration cannot be used together")
+		case endAllDay != allDay:
+			errs.add("end", "start and end must both be dates or both be times")
+		case !start.IsZero() && end.Before(start):
+			errs.add("end", "end is before start")
+		}
+	}
+
+	if e.Duration != "" {
+		if d, err := ParseDuration(e.Duration); err != nil {
+			errs.add("duration", "%s", err)
+		} else if d < 0 {
+			errs.add("duration", "duration must not be negative")
+		}
+	}
+
+	if e.RRule != "" {
+		if err := validateRRule(e.RRule); err != nil {
+			errs.add("rrule", "%s", err)
+		}
+	}
+
+	return errs.err()
+}
+
+// ParseEventTime parses a date or a date and time, dates are reported as all-day
+func ParseEventTime(s string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, true, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, false, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date or time %q", s)
+}
+
+var frequencies = []string{"SECONDLY", "MINUTELY", "HOURLY", "DAILY", "WEEKLY", "MONTHLY", "YEARLY"}
+
+// validateRRule checks the recurrence rule is a list of NAME=VALUE parts with a valid FREQ
+func validateRRule(rrule string) error {
+	freq := ""
+	for _, part := range strings.Split(strings.TrimPrefix(rrule, "RRULE:"), ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || name == "" || value == "" {
+			return fmt.Errorf("invalid rrule part %q", part)
+		}
+		if strings.ToUpper(name) == "FREQ" {
+			freq = strings.ToUpper(value)
+		}
+	}
+	for _, f := range frequencies {
+		if f == freq {
+			return nil
+		}
+	}
+	return fmt.Errorf("rrule requires FREQ to be one of %s", strings.Join(frequencies, ", "))
+}
+
+// Weeks configures week markers. Summary is a text/template with the fields Week, Year
+// and Date, and Term for the weeks of a term.
+type Weeks struct {
+	From    string `yaml:"from,omitempty"`
+	To      string `yaml:"to,omitempty"`
+	Summary string `yaml:"summary,omitempty"`
+}
+
+func (w *Weeks) Validate(needRange bool) error {
+	var errs ValidationErrors
+
+	if needRange || w.From != "" || w.To != "" {
+		from, err := time.Parse(time.DateOnly, w.From)
+		if err != nil {
+			errs.add("from", "from must be a date such as 2024-01-01")
+		}
+		to, err := time.Parse(time.DateOnly, w.To)
+		if err != nil {
+			errs.add("to", "to must be a date such as 2024-12-31")
+		} else if to.Before(from) {
+			errs.add("to", "to is before from")
+		}
+	}
+
+	if w.Summary != "" {
+		if _, err := template.New("summary").Parse(w.Summary); err != nil {
+			errs.add("summary", "summary is invalid: %s", err)
+		}
+	}
+
+	return errs.err()
+}
+
+// Term is a school term, both dates are included
+type Term struct {
+	Name  string `yaml:"name"`
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+func (t *Term) Validate() error {
+	var errs ValidationErrors
+
+	if t.Name == "" {
+		errs.add("name", "name is missing")
+	}
+	start, err := time.Parse(time.DateOnly, t.Start)
+	if err != nil {
+		errs.add("start", "start must be a date such as 2024-09-02")
+	}
+	end, err := time.Parse(time.DateOnly, t.End)
+	if err != nil {
+		errs.add("end", "end must be a date such as 2024-12-20")
+	} else if end.Before(start) {
+		errs.add("end", "end is before start")
+	}
+
+	return errs.err()
+}
+
+// Holidays selects the public holidays of a holidays source. Countries are ISO 3166 codes,
+// optionally followed by a region such as DE-BY.
+type Holidays struct {
+	Countries []string `yaml:"countries"`
+	// Years defaults to the previous, current and next year
+	Years []int `yaml:"years,omitempty"`
+}
+
+// RequireHolidays ensures a holidays source has countries and sensible years
+func RequireHolidays(s *SourceInfo) ValidationErrors {
+	if s.Holidays == nil || len(s.Holidays.Countries) == 0 {
+		return FieldError("holidays", "source type %s requires countries", s.Kind())
+	}
+	var errs ValidationErrors
+	for _, year := range s.Holidays.Years {
+		if year < 1583 || year > 4099 {
+			errs = append(errs, FieldError("holidays", "year %d is outside the Gregorian calendar range 1583-4099", year)...)
+		}
+	}
+	return errs
+}
+
+// VCard configures a vcard source. Path is a .vcf file or a directory of them, the summaries
+// are text/templates with the fields Name, Age and Year. Age is the age reached in the year of
+// an overridden occurrence, it is empty on the recurring event and when the year is unknown.
+type VCard struct {
+	Path               string `yaml:"path"`
+	Summary            string `yaml:"summary,omitempty"`
+	AnniversarySummary string `yaml:"anniversary_summary,omitempty"`
+}
+
+// RequireVCard ensures a vcard source points to readable vCards
+func RequireVCard(s *SourceInfo) ValidationErrors {
+	if s.VCard == nil || s.VCard.Path == "" {
+		return FieldError("vcard", "source type %s requires a path", s.Kind())
+	}
+
+	var errs ValidationErrors
+	if _, err := os.Stat(s.VCard.Path); err != nil {
+		errs.nest("vcard", FieldError("path", "path is not readable: %s", err))
+	}
+	if _, err := template.New("summary").Parse(s.VCard.Summary); err != nil {
+		errs.nest("vcard", FieldError("summary", "summary is invalid: %s", err))
+	}
+	if _, err := template.New("anniversary_summary").Parse(s.VCard.AnniversarySummary); err != nil {
+		errs.nest("vcard", FieldError("anniversary_summary", "anniversary_summary is invalid: %s", err))
+	}
+	return errs
+}
+
+// Mail configures a mail source. Path is an mbox file, a Maildir or a directory of messages.
+type Mail struct {
+	Path string `yaml:"path"`
+}
+
+// RequireMail ensures a mail source points to a readable mailbox
+func RequireMail(s *SourceInfo) ValidationErrors {
+	if s.Mail == nil || s.Mail.Path == "" {
+		return FieldError("mail", "source type %s requires a path", s.Kind())
+	}
+	var errs ValidationErrors
+	if _, err := os.Stat(s.Mail.Path); err != nil {
+		errs.nest("mail", FieldError("path", "path is not readable: %s", err))
+	}
+	return errs
+}
+
+// RequireUrl ensures the source has a valid URL to load the calendar from
+func RequireUrl(s *SourceInfo) ValidationErrors {
+	if s.Url == "" {
+		return FieldError("url", "URL is missing")
+	}
+	if u, err := url.Parse(s.Url); err != nil {
+		return FieldError("url", "URL is invalid")
+	} else if u.Hostname() == "" {
+		return FieldError("url", "URL is invalid (hostname)")
+	}
+	return nil
+}
+
+// RequireEvents ensures a static source defines valid events
+func RequireEvents(s *SourceInfo) ValidationErrors {
+	if len(s.Events) == 0 {
+		return FieldError("events", "source type %s requires events", s.Kind())
+	}
+	var errs ValidationErrors
+	for i, e := range s.Events {
+		errs.nest(indexPath("events", i), e.Validate())
+	}
+	return errs
+}
+
+// RequireWeeks ensures a week source has the range to generate markers for
+func RequireWeeks(s *SourceInfo) ValidationErrors {
+	if s.Weeks == nil {
+		return FieldError("weeks", "source type %s requires weeks", s.Kind())
+	}
+	var errs ValidationErrors
+	errs.nest("weeks", s.Weeks.Validate(true))
+	return errs
+}
+
+// RequireTerms ensures a term source defines valid terms
+func RequireTerms(s *SourceInfo) ValidationErrors {
+	if len(s.Terms) == 0 {
+		return FieldError("terms", "source type %s requires terms", s.Kind())
+	}
+	var errs ValidationErrors
+	for i, t := range s.Terms {
+		errs.nest(indexPath("terms", i), t.Validate())
+	}
+	if s.Weeks != nil {
+		errs.nest("weeks", s.Weeks.Validate(false))
+	}
+	return errs
+}
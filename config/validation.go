@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError describes a single problem found in the config. Path points to the
+// yaml node containing the problem, Field to the offending key inside it (if any).
+type ValidationError struct {
+	Path  string
+	Field string
+	Line  int
+	Msg   string
+}
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	if e.Line > 0 {
+		b.WriteString("line " + strconv.Itoa(e.Line) + ": ")
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	b.WriteString(e.Msg)
+	return b.String()
+}
+
+// ValidationErrors holds every problem found while validating the config
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// FieldError returns a ValidationErrors with a single error for the given field
+func FieldError(field string, format string, args ...any) ValidationErrors {
+	return ValidationErrors{{Field: field, Msg: fmt.Sprintf(format, args...)}}
+}
+
+// add records an error for the given field
+func (e *ValidationErrors) add(field string, format string, args ...any) {
+	*e = append(*e, ValidationError{Field: field, Msg: fmt.Sprintf(format, args...)})
+}
+
+// nest records the errors of a child node, prefixing their paths with path
+func (e *ValidationErrors) nest(path string, err error) {
+	if err == nil {
+		return
+	}
+
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		*e = append(*e, ValidationError{Path: path, Msg: err.Error()})
+		return
+	}
+
+	for _, child := range errs {
+		child.Path = joinPath(path, child.Path)
+		*e = append(*e, child)
+	}
+}
+
+// err returns nil when no errors have been recorded, so callers can compare against nil
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// dedupe drops repeated errors, generic and check specific validation may report the same problem
+func (e ValidationErrors) dedupe() ValidationErrors {
+	var out ValidationErrors
+	for _, err := range e {
+		if !slices.Contains(out, err) {
+			out = append(out, err)
+		}
+	}
+	return out
+}
+
+func joinPath(parent, child string) string {
+	switch {
+	case parent == "":
+		return child
+	case child == "":
+		return parent
+	case strings.HasPrefix(child, "["):
+		return parent + child
+	}
+	return parent + "." + child
+}
+
+func indexPath(key string, i int) string {
+	return key + "[" + strconv.Itoa(i) + "]"
+}
+
+// locate sets the line of each error by walking the yaml document
+func (e ValidationErrors) locate(root *yaml.Node) {
+	for i := range e {
+		if node := lookupNode(root, joinPath(e[i].Path, e[i].Field)); node != nil {
+			e[i].Line = node.Line
+		} else if node := lookupNode(root, e[i].Path); node != nil {
+			e[i].Line = node.Line
+		}
+	}
+}
+
+// lookupNode finds the node at path, e.g. sources[0].info[1].rules[0].check
+func lookupNode(node *yaml.Node, path string) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if path == "" {
+		return node
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(segment, "[")
+		if key != "" {
+			node = mappingValue(node, key)
+		}
+
+		for rest != "" && node != nil {
+			idx, after, _ := strings.Cut(rest, "]")
+			i, err := strconv.Atoi(idx)
+			if err != nil || node.Kind != yaml.SequenceNode || i >= len(node.Content) {
+				return nil
+			}
+			node = node.Content[i]
+			rest = strings.TrimPrefix(after, "[")
+		}
+
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
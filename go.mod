@@ -5,6 +5,7 @@ go 1.18
 require (
 	github.com/arran4/golang-ical v0.2.8
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/text v0.14.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -0,0 +1,221 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+)
+
+func init() {
+	RegisterAction(config.APPEND, actionAppend, config.RequireModifierComponent, config.RequireModifierData)
+	RegisterAction(config.PREPEND, actionPrepend, config.RequireModifierComponent, config.RequireModifierData)
+	RegisterAction(config.REPLACE, actionReplace, config.RequireModifierComponent)
+	RegisterAction(config.SET, actionSet, config.RequireModifierComponent)
+	RegisterAction(config.REMOVE, actionRemove, config.RequireModifierComponent)
+	RegisterAction(config.DEFAULT, actionDefault, config.RequireModifierComponent, config.RequireModifierData)
+	RegisterAction(config.REMOVE_PARAM, actionRemoveParam, config.RequireModifierComponent, config.RequireModifierData)
+	RegisterAction(config.SUBSTITUTE, actionSubstitute, config.RequireModifierComponent, config.RequireModifierPattern)
+	RegisterAction(config.TEMPLATE, actionTemplate, config.RequireModifierComponent, config.RequireModifierTemplate)
+}
+
+// errMissingProperty is returned by actions requiring a property the event does not have
+var errMissingProperty = errors.New("event is missing property")
+
+func missingProperty(prop ics.ComponentProperty) error {
+	return fmt.Errorf("%w %s", errMissingProperty, prop)
+}
+
+var (
+	patternLock sync.Mutex
+	patterns    = map[string]*regexp.Regexp{}
+	templates   = map[string]*template.Template{}
+)
+
+// compilePattern compiles the pattern once, and returns the cached regexp afterwards
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternLock.Lock()
+	defer patternLock.Unlock()
+
+	if re, ok := patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns[pattern] = re
+	return re, nil
+}
+
+// parseTemplate parses the template once, and returns the cached template afterwards
+func parseTemplate(text string) (*template.Template, error) {
+	patternLock.Lock()
+	defer patternLock.Unlock()
+
+	if tmpl, ok := templates[text]; ok {
+		return tmpl, nil
+	}
+	tmpl, err := template.New("data").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	templates[text] = tmpl
+	return tmpl, nil
+}
+
+// actionAppend appends the modifier data to the component
+func actionAppend(e *ics.VEvent, ctx ModifierContext) error {
+	prop := ics.ComponentProperty(ctx.Modifier.Component)
+	comp := e.GetProperty(prop)
+	if comp == nil {
+		return missingProperty(prop)
+	}
+	comp.Value += ctx.Modifier.Data
+	return nil
+}
+
+// actionPrepend prepends the modifier data to the component
+func actionPrepend(e *ics.VEvent, ctx ModifierContext) error {
+	prop := ics.ComponentProperty(ctx.Modifier.Component)
+	comp := e.GetProperty(prop)
+	if comp == nil {
+		return missingProperty(prop)
+	}
+	comp.Value = ctx.Modifier.Data + comp.Value
+	return nil
+}
+
+// actionReplace replaces the value of the component with the modifier data
+func actionReplace(e *ics.VEvent, ctx ModifierContext) error {
+	prop := ics.ComponentProperty(ctx.Modifier.Component)
+	comp := e.GetProperty(prop)
+	if comp == nil {
+		return missingProperty(prop)
+	}
+	comp.Value = ctx.Modifier.Data
+	return nil
+}
+
+// actionSet sets the component to the modifier data, creating it when missing
+func actionSet(e *ics.VEvent, ctx ModifierContext) error {
+	e.SetProperty(ics.ComponentProperty(ctx.Modifier.Component), ctx.Modifier.Data)
+	return nil
+}
+
+// actionRemove removes every instance of the component
+func actionRemove(e *ics.VEvent, ctx ModifierContext) error {
+	removeProperty(&e.ComponentBase, ics.ComponentProperty(ctx.Modifier.Component))
+	return nil
+}
+
+// actionDefault sets the component to the modifier data if the event does not have it
+func actionDefault(e *ics.VEvent, ctx ModifierContext) error {
+	prop := ics.ComponentProperty(ctx.Modifier.Component)
+	if e.GetProperty(prop) == nil {
+		e.SetProperty(prop, ctx.Modifier.Data)
+	}
+	return nil
+}
+
+// actionRemoveParam removes the parameter named in the modifier data from every instance of the component
+func actionRemoveParam(e *ics.VEvent, ctx ModifierContext) error {
+	param := strings.ToUpper(ctx.Modifier.Data)
+	for i := range e.Properties {
+		if e.Properties[i].IANAToken == ctx.Modifier.Component {
+			delete(e.Properties[i].ICalParameters, param)
+		}
+	}
+	return nil
+}
+
+// removeProperty removes every instance of the property
+func removeProperty(cb *ics.ComponentBase, prop ics.ComponentProperty) {
+	kept := cb.Properties[:0]
+	for _, p := range cb.Properties {
+		if p.IANAToken != string(prop) {
+			kept = append(kept, p)
+		}
+	}
+	cb.Properties = kept
+}
+
+// actionSubstitute replaces every match of the modifier pattern in the component with the
+// modifier data, $1 or ${name} in the data refer to capture groups. The pattern is matched
+// against the unescaped text.
+func actionSubstitute(e *ics.VEvent, ctx ModifierContext) error {
+	prop := ics.ComponentProperty(ctx.Modifier.Component)
+	comp := e.GetProperty(prop)
+	if comp == nil {
+		return missingProperty(prop)
+	}
+
+	re, err := compilePattern(ctx.Modifier.Pattern)
+	if err != nil {
+		return err
+	}
+	comp.Value = ics.ToText(re.ReplaceAllString(ics.FromText(comp.Value), ctx.Modifier.Data))
+	return nil
+}
+
+// actionTemplate sets the component to the modifier data rendered as a text/template
+func actionTemplate(e *ics.VEvent, ctx ModifierContext) error {
+	tmpl, err := parseTemplate(ctx.Modifier.Data)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, newEventData(e, ctx.Calendar)); err != nil {
+		return err
+	}
+	e.SetProperty(ics.ComponentProperty(ctx.Modifier.Component), ics.ToText(b.String()))
+	return nil
+}
+
+// EventData exposes the fields of an event to templates, text is unescaped
+type EventData struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	// Source is the name of the source the event was loaded from
+	Source string
+	Start  time.Time
+	End    time.Time
+	AllDay bool
+
+	event *ics.VEvent
+}
+
+func newEventData(e *ics.VEvent, c *LoadediCal) EventData {
+	d := EventData{
+		UID:    e.Id(),
+		AllDay: isAllDay(e),
+		event:  e,
+	}
+	d.Summary = d.Property(string(ics.ComponentPropertySummary))
+	d.Description = d.Property(string(ics.ComponentPropertyDescription))
+	d.Location = d.Property(string(ics.ComponentPropertyLocation))
+	if c != nil {
+		d.Source = c.Source().Name
+	}
+	if start, end, err := eventSpan(e); err == nil {
+		d.Start, d.End = start, end
+	}
+	return d
+}
+
+// Property returns the value of any property of the event, or an empty string
+func (d EventData) Property(name string) string {
+	if p := d.event.GetProperty(ics.ComponentProperty(name)); p != nil {
+		return ics.FromText(p.Value)
+	}
+	return ""
+}
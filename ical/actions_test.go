@@ -0,0 +1,191 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func applyAction(t *testing.T, e *ics.VEvent, m config.Modifier) *ics.VEvent {
+	action, ok := lookupAction(m.Action)
+	assert.True(t, ok)
+	assert.NoError(t, action(e, ModifierContext{Modifier: &m, Calendar: newLoadedCal("school")}))
+	return e
+}
+
+func TestActionSubstitute(t *testing.T) {
+	e := newEventWithProperty(ics.ComponentPropertySummary, "CS101 - Lecture (Room 4)")
+	applyAction(t, e, config.Modifier{
+		Component: "SUMMARY",
+		Action:    config.SUBSTITUTE,
+		Pattern:   `^(\w+) - (\w+).*$`,
+		Data:      "$2 $1",
+	})
+	assert.Equal(t, "Lecture CS101", e.GetProperty(ics.ComponentPropertySummary).Value)
+
+	e = newEventWithProperty(ics.ComponentPropertySummary, "Room 4, Room 5")
+	applyAction(t, e, config.Modifier{Component: "SUMMARY", Action: config.SUBSTITUTE, Pattern: `Room (?P<nr>\d)`, Data: "R${nr}"})
+	assert.Equal(t, `R4\, R5`, e.GetProperty(ics.ComponentPropertySummary).Value)
+
+	// the pattern sees unescaped text, and the result is escaped again
+	e = ics.NewEvent("1")
+	e.SetSummary("Room 4, Building A")
+	applyAction(t, e, config.Modifier{Component: "SUMMARY", Action: config.SUBSTITUTE, Pattern: `, (Building \w)$`, Data: "\n$1"})
+	assert.Equal(t, `Room 4\nBuilding A`, e.GetProperty(ics.ComponentPropertySummary).Value)
+}
+
+func TestActionTemplate(t *testing.T) {
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	e := newEventWithSpan("1", start, time.Hour)
+	e.SetSummary("Lecture")
+	e.SetLocation("Room 4")
+	e.SetProperty("X-COURSE", "CS101")
+
+	applyAction(t, e, config.Modifier{
+		Component: "DESCRIPTION",
+		Action:    config.TEMPLATE,
+		Data:      `{{.Summary}} @ {{.Location}} ({{.Source}}, {{.Start.Format "15:04"}}-{{.End.Format "15:04"}}) {{.Property "X-COURSE"}}{{.Property "X-MISSING"}}`,
+	})
+	assert.Equal(t, `Lecture @ Room 4 (school\, 09:00-10:00) CS101`, e.GetProperty(ics.ComponentPropertyDescription).Value)
+
+	// escaped values are unescaped for the template, and rendered newlines produce a valid calendar
+	e.SetLocation("Room 4, Building A")
+	applyAction(t, e, config.Modifier{
+		Component: "DESCRIPTION",
+		Action:    config.TEMPLATE,
+		Data:      "{{.Summary}}\n{{.Location}}",
+	})
+	assert.Equal(t, `Lecture\nRoom 4\, Building A`, e.GetProperty(ics.ComponentPropertyDescription).Value)
+
+	cal := ics.NewCalendar()
+	cal.AddVEvent(e)
+	parsed, err := ics.ParseCalendar(strings.NewReader(cal.Serialize()))
+	assert.NoError(t, err)
+	if assert.Len(t, parsed.Events(), 1) {
+		assert.Equal(t, "Lecture\nRoom 4, Building A", ics.FromText(parsed.Events()[0].GetProperty(ics.ComponentPropertyDescription).Value))
+	}
+}
+
+func TestPropertyActions(t *testing.T) {
+	e := ics.NewEvent("1")
+	e.SetSummary("Meeting", &ics.KeyValues{Key: "LANGUAGE", Value: []string{"en"}})
+	e.AddAttendee("mailto:a@example.com", ics.WithRSVP(true))
+	e.AddAttendee("mailto:b@example.com", ics.WithRSVP(true))
+
+	// SET creates and overwrites
+	applyAction(t, e, config.Modifier{Component: "LOCATION", Action: config.SET, Data: "Office"})
+	assert.Equal(t, "Office", e.GetProperty(ics.ComponentPropertyLocation).Value)
+	applyAction(t, e, config.Modifier{Component: "LOCATION", Action: config.SET, Data: "Home"})
+	assert.Equal(t, "Home", e.GetProperty(ics.ComponentPropertyLocation).Value)
+
+	// DEFAULT only sets missing properties
+	applyAction(t, e, config.Modifier{Component: "LOCATION", Action: config.DEFAULT, Data: "Office"})
+	assert.Equal(t, "Home", e.GetProperty(ics.ComponentPropertyLocation).Value)
+	applyAction(t, e, config.Modifier{Component: "CLASS", Action: config.DEFAULT, Data: "PUBLIC"})
+	assert.Equal(t, "PUBLIC", e.GetProperty(ics.ComponentPropertyClass).Value)
+
+	// REMOVE_PARAM removes the parameter from every instance
+	applyAction(t, e, config.Modifier{Component: "ATTENDEE", Action: config.REMOVE_PARAM, Data: "rsvp"})
+	for _, a := range e.Attendees() {
+		assert.NotContains(t, a.ICalParameters, "RSVP")
+	}
+
+	// REMOVE removes every instance
+	applyAction(t, e, config.Modifier{Component: "ATTENDEE", Action: config.REMOVE})
+	assert.Empty(t, e.Attendees())
+	assert.NotNil(t, e.GetProperty(ics.ComponentPropertySummary))
+
+	// APPEND keeps the parameters of the property
+	applyAction(t, e, config.Modifier{Component: "SUMMARY", Action: config.APPEND, Data: "!"})
+	assert.Equal(t, "Meeting!", e.GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Equal(t, []string{"en"}, e.GetProperty(ics.ComponentPropertySummary).ICalParameters["LANGUAGE"])
+}
+
+func TestActionsOnMissingProperty(t *testing.T) {
+	for _, action := range []config.Action{config.APPEND, config.PREPEND, config.REPLACE, config.SUBSTITUTE} {
+		e := ics.NewEvent("1")
+		fn, ok := lookupAction(action)
+		assert.True(t, ok)
+		err := fn(e, ModifierContext{Modifier: &config.Modifier{Component: "DESCRIPTION", Action: action, Pattern: "a"}})
+		assert.ErrorIs(t, err, errMissingProperty, action)
+		assert.Nil(t, e.GetProperty(ics.ComponentPropertyDescription))
+	}
+
+	// Modify does not panic on missing properties
+	log.Init("ERROR", config.Notification{})
+	cal := &LoadediCal{source: config.SourceInfo{Modifiers: []config.Modifier{
+		{Component: "DESCRIPTION", Action: config.APPEND, Data: "!"},
+		{Component: "SUMMARY", Action: config.APPEND, Data: "!"},
+	}}}
+	e := cal.Modify(newEventWithProperty(ics.ComponentPropertySummary, "Meeting"))
+	assert.Equal(t, "Meeting!", e.GetProperty(ics.ComponentPropertySummary).Value)
+}
+
+func TestActionAlarm(t *testing.T) {
+	e := ics.NewEvent("1")
+
+	// legacy display alarm from data, not added twice
+	applyAction(t, e, config.Modifier{Name: "Soon", Action: config.ALARM, Data: "-PT15M"})
+	applyAction(t, e, config.Modifier{Name: "Soon", Action: config.ALARM, Data: "-PT15M"})
+	assert.Len(t, e.Alarms(), 1)
+	assert.Equal(t, "Soon", e.Alarms()[0].GetProperty(ics.ComponentPropertyDescription).Value)
+
+	// the same trigger relative to the end is a different alarm
+	applyAction(t, e, config.Modifier{Name: "End", Action: config.ALARM, Alarm: &config.Alarm{Trigger: "-PT15M", Related: "end"}})
+	assert.Len(t, e.Alarms(), 2)
+	assert.Equal(t, []string{"END"}, e.Alarms()[1].GetProperty(ics.ComponentPropertyTrigger).ICalParameters["RELATED"])
+
+	// equal durations written differently are duplicates
+	applyAction(t, e, config.Modifier{Action: config.ALARM, Alarm: &config.Alarm{Trigger: "-PT60M"}})
+	applyAction(t, e, config.Modifier{Action: config.ALARM, Alarm: &config.Alarm{Trigger: "-PT1H"}})
+	assert.Len(t, e.Alarms(), 3)
+
+	applyAction(t, e, config.Modifier{Action: config.ALARM, Alarm: &config.Alarm{
+		Action:    config.AlarmEmail,
+		Trigger:   "20240304T090000Z",
+		Summary:   "Reminder",
+		Attendees: []string{"mailto:a@example.com", "mailto:b@example.com"},
+		Repeat:    2,
+		Duration:  "PT5M",
+	}})
+	assert.Len(t, e.Alarms(), 4)
+	email := e.Alarms()[3]
+	assert.Equal(t, "EMAIL", email.GetProperty(ics.ComponentPropertyAction).Value)
+	assert.Equal(t, []string{"DATE-TIME"}, email.GetProperty(ics.ComponentPropertyTrigger).ICalParameters["VALUE"])
+	assert.Equal(t, "Reminder", email.GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Equal(t, "2", email.GetProperty(ics.ComponentProperty(ics.PropertyRepeat)).Value)
+	assert.Equal(t, "PT5M", email.GetProperty(ics.ComponentProperty(ics.PropertyDuration)).Value)
+	attendees := 0
+	for _, p := range email.Properties {
+		if p.IANAToken == string(ics.ComponentPropertyAttendee) {
+			attendees++
+		}
+	}
+	assert.Equal(t, 2, attendees)
+}
+
+func TestActionRemoveAlarms(t *testing.T) {
+	newEvent := func() *ics.VEvent {
+		e := ics.NewEvent("1")
+		for _, action := range []ics.Action{ics.ActionDisplay, ics.ActionAudio} {
+			a := e.AddAlarm()
+			a.SetAction(action)
+			a.SetTrigger("-PT15M")
+		}
+		return e
+	}
+
+	e := applyAction(t, newEvent(), config.Modifier{Action: config.REMOVE_ALARMS})
+	assert.Empty(t, e.Alarms())
+
+	e = applyAction(t, newEvent(), config.Modifier{Action: config.REMOVE_ALARMS, Alarm: &config.Alarm{Rules: []config.Rule{
+		{Component: "ACTION", Check: FilterEqualsTerm, Data: []string{"audio"}},
+	}}})
+	assert.Len(t, e.Alarms(), 1)
+	assert.Equal(t, "DISPLAY", e.Alarms()[0].GetProperty(ics.ComponentPropertyAction).Value)
+}
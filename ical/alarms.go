@@ -0,0 +1,124 @@
+package ical
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+)
+
+func init() {
+	RegisterAction(config.ALARM, actionAlarm, config.RequireModifierAlarm)
+	RegisterAction(config.REMOVE_ALARMS, actionRemoveAlarms)
+}
+
+// actionAlarm adds an alarm, unless the event already has an alarm with the same action and trigger.
+// Without alarm options a display alarm is added using the modifier data as trigger.
+func actionAlarm(e *ics.VEvent, ctx ModifierContext) error {
+	alarm := config.Alarm{}
+	if ctx.Modifier.Alarm != nil {
+		alarm = *ctx.Modifier.Alarm
+	}
+	if alarm.Action == "" {
+		alarm.Action = config.AlarmDisplay
+	}
+	if alarm.Trigger == "" {
+		alarm.Trigger = ctx.Modifier.Data
+	}
+	if alarm.Description == "" {
+		alarm.Description = ctx.Modifier.Name
+	}
+
+	var triggerParams []ics.PropertyParameter
+	if config.IsAbsoluteTrigger(alarm.Trigger) {
+		triggerParams = append(triggerParams, ics.WithValue(string(ics.ValueDataTypeDateTime)))
+	} else if strings.EqualFold(alarm.Related, "END") {
+		triggerParams = append(triggerParams, &ics.KeyValues{Key: string(ics.ParameterRelated), Value: []string{"END"}})
+	}
+
+	for _, existing := range e.Alarms() {
+		if sameAlarm(existing, alarm) {
+			return nil
+		}
+	}
+
+	a := e.AddAlarm()
+	a.SetAction(ics.Action(alarm.Action))
+	a.SetTrigger(alarm.Trigger, triggerParams...)
+
+	switch alarm.Action {
+	case config.AlarmDisplay:
+		a.SetProperty(ics.ComponentPropertyDescription, alarm.Description)
+	case config.AlarmAudio:
+		if alarm.Attach != "" {
+			a.AddProperty(ics.ComponentPropertyAttach, alarm.Attach)
+		}
+	case config.AlarmEmail:
+		a.SetProperty(ics.ComponentPropertyDescription, alarm.Description)
+		a.SetProperty(ics.ComponentPropertySummary, alarm.Summary)
+		for _, attendee := range alarm.Attendees {
+			a.AddProperty(ics.ComponentPropertyAttendee, attendee)
+		}
+	}
+
+	if alarm.Repeat > 0 {
+		a.SetProperty(ics.ComponentProperty(ics.PropertyRepeat), strconv.Itoa(alarm.Repeat))
+		a.SetProperty(ics.ComponentProperty(ics.PropertyDuration), alarm.Duration)
+	}
+	return nil
+}
+
+// sameAlarm reports whether the existing alarm has the same action, trigger and relation as alarm
+func sameAlarm(existing *ics.VAlarm, alarm config.Alarm) bool {
+	action := existing.GetProperty(ics.ComponentPropertyAction)
+	trigger := existing.GetProperty(ics.ComponentPropertyTrigger)
+	if action == nil || trigger == nil {
+		return false
+	}
+	if !strings.EqualFold(action.Value, string(alarm.Action)) || !sameDuration(trigger.Value, alarm.Trigger) {
+		return false
+	}
+
+	related := "START"
+	if r, ok := trigger.ICalParameters[string(ics.ParameterRelated)]; ok && len(r) == 1 {
+		related = strings.ToUpper(r[0])
+	}
+	want := "START"
+	if strings.EqualFold(alarm.Related, "END") {
+		want = "END"
+	}
+	return related == want
+}
+
+// sameDuration compares triggers, treating equal durations written differently (-PT60M, -PT1H) as equal
+func sameDuration(a, b string) bool {
+	if a == b {
+		return true
+	}
+	da, errA := config.ParseDuration(a)
+	db, errB := config.ParseDuration(b)
+	return errA == nil && errB == nil && da == db
+}
+
+// actionRemoveAlarms removes the alarms of the event matching any of the alarm rules of the
+// modifier, or all alarms when there are none
+func actionRemoveAlarms(e *ics.VEvent, ctx ModifierContext) error {
+	var rules []config.Rule
+	if ctx.Modifier.Alarm != nil {
+		rules = ctx.Modifier.Alarm.Rules
+	}
+
+	// alarms are matched as if they were events, so every check can be used on them
+	matcher := &LoadediCal{source: config.SourceInfo{Rules: rules}}
+	kept := e.Components[:0]
+	for _, comp := range e.Components {
+		alarm, ok := comp.(*ics.VAlarm)
+		if ok && (len(rules) == 0 || matcher.Check(&ics.VEvent{ComponentBase: alarm.ComponentBase})) {
+			continue
+		}
+		kept = append(kept, comp)
+	}
+	e.Components = kept
+	return nil
+}
@@ -2,6 +2,7 @@ package ical
 
 import (
 	"strings"
+	"time"
 
 	"github.com/Fesaa/ical-merger/config"
 	"github.com/Fesaa/ical-merger/log"
@@ -17,6 +18,8 @@ const (
 	FilterEqualsTerm = "EQUALS"
 	// checks if the event does not equal any of the strings in the rule
 	FilterNotEqualsTerm = "NOT_EQUALS"
+	// checks if the event contains any of the strings in the rule, allowing for small typos
+	FilterFuzzyTerm = "FUZZY"
 
 	// checks if the event is the first of the day
 	ModifierFirstOfDayTerm = "FIRST_OF_DAY"
@@ -38,29 +41,41 @@ func (c *LoadediCal) Check(event *ics.VEvent) bool {
 	return false
 }
 
+func init() {
+	RegisterCheck(FilterContainsTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.filterContains(ctx.Rule, e)
+	}, config.RequireComponent, config.RequireData)
+	RegisterCheck(FilterNotContainsTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.filterNotContains(ctx.Rule, e)
+	}, config.RequireComponent, config.RequireData)
+	RegisterCheck(FilterEqualsTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.filterEquals(ctx.Rule, e)
+	}, config.RequireComponent, config.RequireData)
+	RegisterCheck(FilterNotEqualsTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.filterNotEquals(ctx.Rule, e)
+	}, config.RequireComponent, config.RequireData)
+	RegisterCheck(FilterFuzzyTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.filterFuzzy(ctx.Rule, e)
+	}, config.RequireComponent, config.RequireData)
+
+	RegisterCheck(ModifierFirstOfDayTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.modifierFirstOfDay(e)
+	})
+	RegisterCheck(ModifierFirstOfMonthTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.modifierFirstOfMonth(e)
+	})
+	RegisterCheck(ModifierFirstOfYearTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.modifierFirstOfYear(e)
+	})
+}
+
 func (c *LoadediCal) apply(r *config.Rule, event *ics.VEvent) bool {
-	switch r.Check {
-	// Filters
-	case FilterContainsTerm:
-		return c.filterContains(r, event)
-	case FilterNotContainsTerm:
-		return c.filterNotContains(r, event)
-	case FilterEqualsTerm:
-		return c.filterEquals(r, event)
-	case FilterNotEqualsTerm:
-		return c.filterNotEquals(r, event)
-
-	// Modifiers
-	case ModifierFirstOfDayTerm:
-		return c.modifierFirstOfDay(event)
-	case ModifierFirstOfMonthTerm:
-		return c.modifierFirstOfMonth(event)
-	case ModifierFirstOfYearTerm:
-		return c.modifierFirstOfYear(event)
-	default:
-	}
-	log.Logger.Warn("Check not found", "rule_name", r.Name, "check", r.Check)
-	return false
+	check, ok := lookupCheck(r.Check)
+	if !ok {
+		log.Logger.Warn("Check not found", "rule_name", r.Name, "check", r.Check)
+		return false
+	}
+	return check(event, RuleContext{Rule: r, Calendar: c})
 }
 
 /* Filters */
@@ -97,8 +112,112 @@ func (c *LoadediCal) filterNotEquals(r *config.Rule, event *ics.VEvent) bool {
 	return !c.filterEquals(r, event)
 }
 
+// filterFuzzy checks if the event contains any of the strings in the rule, allowing up to
+// Distance (default 1) insertions, deletions or substitutions
+func (c *LoadediCal) filterFuzzy(r *config.Rule, event *ics.VEvent) bool {
+	p := event.GetProperty(ics.ComponentProperty(r.Component))
+	if p == nil {
+		return false
+	}
+
+	maxDistance := r.MaxDistance()
+	value := []rune(r.Transform(p.Value))
+	for _, s := range r.Data {
+		if substringDistance([]rune(r.Transform(s)), value) <= maxDistance {
+			return true
+		}
+	}
+	return false
+}
+
+// substringDistance returns the smallest edit distance between needle and any substring of
+// haystack, swapping two adjacent characters counts as a single edit
+func substringDistance(needle, haystack []rune) int {
+	// prev[j] is the distance of the needle prefix matched so far ending at haystack[j-1],
+	// starting at zero everywhere so the match may start anywhere in the haystack
+	before := make([]int, len(haystack)+1)
+	prev := make([]int, len(haystack)+1)
+	cur := make([]int, len(haystack)+1)
+	for i := 1; i <= len(needle); i++ {
+		cur[0] = i
+		for j := 1; j <= len(haystack); j++ {
+			cost := 1
+			if needle[i-1] == haystack[j-1] {
+				cost = 0
+			}
+			cur[j] = minOf(prev[j-1]+cost, prev[j]+1, cur[j-1]+1)
+			if i > 1 && j > 1 && needle[i-1] == haystack[j-2] && needle[i-2] == haystack[j-1] {
+				cur[j] = minOf(cur[j], before[j-2]+1)
+			}
+		}
+		before, prev, cur = prev, cur, before
+	}
+
+	best := len(needle)
+	for _, d := range prev {
+		best = minOf(best, d)
+	}
+	return best
+}
+
+func minOf(first int, rest ...int) int {
+	for _, v := range rest {
+		if v < first {
+			first = v
+		}
+	}
+	return first
+}
+
 /* Modifiers */
 
+// firstEvent is the earliest event of a period
+type firstEvent struct {
+	event *ics.VEvent
+	start time.Time
+}
+
+// positionalIndex holds the first event of every day, month and year
+type positionalIndex struct {
+	day   map[string]firstEvent
+	month map[string]firstEvent
+	year  map[string]firstEvent
+}
+
+// positional returns the index of the first events among the events currently in scope.
+// Before rules have been applied all events are in scope, afterwards only the kept ones.
+func (c *LoadediCal) positional() *positionalIndex {
+	if c.firsts != nil {
+		return c.firsts
+	}
+
+	idx := &positionalIndex{day: map[string]firstEvent{}, month: map[string]firstEvent{}, year: map[string]firstEvent{}}
+	for _, e := range c.scope() {
+		start, err := e.GetStartAt()
+		if err != nil {
+			continue
+		}
+		local := start.In(time.Local)
+		keepFirst(idx.day, local.Format(time.DateOnly), e, start)
+		keepFirst(idx.month, local.Format("2006-01"), e, start)
+		keepFirst(idx.year, local.Format("2006"), e, start)
+	}
+	c.firsts = idx
+	return idx
+}
+
+func keepFirst(period map[string]firstEvent, key string, event *ics.VEvent, start time.Time) {
+	if first, ok := period[key]; !ok || start.Before(first.start) {
+		period[key] = firstEvent{event: event, start: start}
+	}
+}
+
+// isFirst checks if no other event in the period starts before the event
+func isFirst(period map[string]firstEvent, key string, event *ics.VEvent, start time.Time) bool {
+	first, ok := period[key]
+	return !ok || first.event == event || start.Before(first.start)
+}
+
 // modifierFirstOfDay checks if the event is the first of the day
 func (c *LoadediCal) modifierFirstOfDay(event *ics.VEvent) bool {
 	start, e := event.GetStartAt()
@@ -106,9 +225,7 @@ func (c *LoadediCal) modifierFirstOfDay(event *ics.VEvent) bool {
 		return false
 	}
 
-	first := start.Day() > c.currentDay
-	c.currentDay = start.Day()
-	return first
+	return isFirst(c.positional().day, start.In(time.Local).Format(time.DateOnly), event, start)
 }
 
 // modifierFirstOfMonth checks if the event is the first of the month
@@ -118,9 +235,7 @@ func (c *LoadediCal) modifierFirstOfMonth(event *ics.VEvent) bool {
 		return false
 	}
 
-	first := start.Month() > c.currentMonth
-	c.currentMonth = start.Month()
-	return first
+	return isFirst(c.positional().month, start.In(time.Local).Format("2006-01"), event, start)
 }
 
 // modifierFirstOfYear checks if the event is the first of the year
@@ -130,7 +245,5 @@ func (c *LoadediCal) modifierFirstOfYear(event *ics.VEvent) bool {
 		return false
 	}
 
-	first := start.Year() > c.currentYear
-	c.currentYear = start.Year()
-	return first
+	return isFirst(c.positional().year, start.In(time.Local).Format("2006"), event, start)
 }
@@ -0,0 +1,137 @@
+package ical
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+)
+
+const (
+	// checks if the event overlaps with an event published by the source in the rule
+	FilterOverlapsWithTerm = "OVERLAPS_WITH"
+)
+
+func init() {
+	RegisterCheck(FilterOverlapsWithTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.filterOverlapsWith(ctx.Rule, e)
+	}, config.RequireSource)
+}
+
+// ConflictEvent is one side of a Conflict
+type ConflictEvent struct {
+	Source  string    `json:"source"`
+	UID     string    `json:"uid"`
+	Summary string    `json:"summary,omitempty"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+}
+
+// Conflict describes two events overlapping in time, Start and End mark the overlap
+type Conflict struct {
+	Start  time.Time       `json:"start"`
+	End    time.Time       `json:"end"`
+	Events []ConflictEvent `json:"events"`
+}
+
+// ConflictReport lists every conflict found in the merged calendar of an endpoint
+type ConflictReport struct {
+	Name      string     `json:"name"`
+	Generated time.Time  `json:"generated"`
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+type spanEvent struct {
+	ConflictEvent
+	event *ics.VEvent
+}
+
+func newSpanEvent(source string, e *ics.VEvent) (spanEvent, bool) {
+	start, end, err := eventSpan(e)
+	if err != nil {
+		return spanEvent{}, false
+	}
+
+	se := spanEvent{
+		ConflictEvent: ConflictEvent{Source: source, UID: e.Id(), Start: start, End: end},
+		event:         e,
+	}
+	if p := e.GetProperty(ics.ComponentPropertySummary); p != nil {
+		se.Summary = p.Value
+	}
+	return se, true
+}
+
+// findConflicts returns every pair of overlapping events. Events from the same source
+// are only compared when sameSource is set.
+func findConflicts(events []spanEvent, sameSource bool) []Conflict {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+
+	conflicts := []Conflict{}
+	for i, a := range events {
+		for _, b := range events[i+1:] {
+			// events are sorted, nothing further can overlap with a
+			if b.Start.After(a.End) || (b.Start.Equal(a.End) && !a.Start.Equal(a.End)) {
+				break
+			}
+			if a.Source == b.Source && !sameSource {
+				continue
+			}
+			if !overlaps(a.Start, a.End, b.Start, b.End) {
+				continue
+			}
+
+			conflicts = append(conflicts, Conflict{
+				Start:  latest(a.Start, b.Start),
+				End:    earliest(a.End, b.End),
+				Events: []ConflictEvent{a.ConflictEvent, b.ConflictEvent},
+			})
+		}
+	}
+	return conflicts
+}
+
+// filterOverlapsWith checks if the event overlaps with an event of the source in the rule. Only the
+// events the source publishes count, those dropped by its rules or hidden by its privacy do not.
+func (c *LoadediCal) filterOverlapsWith(r *config.Rule, event *ics.VEvent) bool {
+	start, end, err := eventSpan(event)
+	if err != nil {
+		return false
+	}
+
+	other, events := c.siblingEvents(r)
+	if other == nil {
+		return false
+	}
+	privacy := c.parent.privacy(other.Source())
+	for _, e := range events {
+		if e == event {
+			continue
+		}
+		if privacyLevel(e, privacy) == config.PrivacyHidden {
+			continue
+		}
+		s, en, err := eventSpan(e)
+		if err == nil && overlaps(start, end, s, en) {
+			return true
+		}
+	}
+	return false
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,85 @@
+package ical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEventWithSpan(id string, start time.Time, d time.Duration) *ics.VEvent {
+	e := ics.NewEvent(id)
+	e.SetStartAt(start)
+	e.SetEndAt(start.Add(d))
+	return e
+}
+
+func newLoadedCal(name string, events ...*ics.VEvent) *LoadediCal {
+	return &LoadediCal{source: config.SourceInfo{Name: name}, events: events, original: events}
+}
+
+func TestFindConflicts(t *testing.T) {
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	var spans []spanEvent
+	for _, se := range []struct {
+		source string
+		event  *ics.VEvent
+	}{
+		{"work", newEventWithSpan("w1", day.Add(9*time.Hour), time.Hour)},
+		{"work", newEventWithSpan("w2", day.Add(10*time.Hour), time.Hour)},
+		{"personal", newEventWithSpan("p1", day.Add(9*time.Hour+30*time.Minute), time.Hour)},
+		{"personal", newEventWithSpan("p2", day.Add(11*time.Hour), time.Hour)},
+	} {
+		s, ok := newSpanEvent(se.source, se.event)
+		assert.True(t, ok)
+		spans = append(spans, s)
+	}
+
+	conflicts := findConflicts(spans, false)
+	assert.Len(t, conflicts, 2)
+	assert.Equal(t, "w1", conflicts[0].Events[0].UID)
+	assert.Equal(t, "p1", conflicts[0].Events[1].UID)
+	assert.Equal(t, day.Add(9*time.Hour+30*time.Minute), conflicts[0].Start)
+	assert.Equal(t, day.Add(10*time.Hour), conflicts[0].End)
+	assert.Equal(t, "p1", conflicts[1].Events[0].UID)
+	assert.Equal(t, "w2", conflicts[1].Events[1].UID)
+
+	// back to back events in the same source do not overlap
+	assert.Len(t, findConflicts(spans, true), 2)
+}
+
+func TestFilterOverlapsWith(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	work := newLoadedCal("work",
+		newEventWithSpan("w1", day.Add(9*time.Hour), time.Hour),
+		newEventWithSpan("w2", day.Add(13*time.Hour), time.Hour),
+		newEventWithSpan("w3", day.Add(16*time.Hour), time.Hour),
+	)
+	private := newEventWithSpan("p3", day.Add(16*time.Hour), time.Hour)
+	private.SetClass(ics.ClassificationPrivate)
+	personal := newLoadedCal("personal",
+		newEventWithSpan("p1", day.Add(9*time.Hour+30*time.Minute), time.Hour),
+		newEventWithSpan("p2", day.Add(13*time.Hour), time.Hour),
+		private,
+	)
+	// personal drops p2 and hides private events
+	personal.source.Rules = []config.Rule{{Check: FilterNotEqualsTerm, Component: "UID", Data: []string{"p2"}}}
+	personal.source.Privacy = &config.Privacy{Private: config.PrivacyHidden}
+	parent := &CustomCalender{loaded: []*LoadediCal{work, personal}}
+	work.parent, personal.parent = parent, parent
+
+	rule := config.Rule{Check: FilterOverlapsWithTerm, Source: "personal"}
+	assert.True(t, work.filterOverlapsWith(&rule, work.original[0]))
+	// only events the other source publishes count
+	assert.False(t, work.filterOverlapsWith(&rule, work.original[1]))
+	assert.False(t, work.filterOverlapsWith(&rule, work.original[2]))
+
+	rule.Source = "missing"
+	assert.False(t, work.filterOverlapsWith(&rule, work.original[0]))
+}
@@ -0,0 +1,131 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	"gopkg.in/yaml.v3"
+)
+
+// resolveData returns a copy of the source with the external data of every rule loaded
+func (c *CustomCalender) resolveData(source config.SourceInfo) config.SourceInfo {
+	source.Rules = c.resolveRules(source.Rules)
+	source.Modifiers = c.resolveModifiers(source.Modifiers)
+	return source
+}
+
+func (c *CustomCalender) resolveModifiers(modifiers []config.Modifier) []config.Modifier {
+	if modifiers == nil {
+		return nil
+	}
+
+	resolved := make([]config.Modifier, len(modifiers))
+	for i, m := range modifiers {
+		m.Filters = c.resolveRules(m.Filters)
+		m.Else = c.resolveModifiers(m.Else)
+		if m.Alarm != nil {
+			alarm := *m.Alarm
+			alarm.Rules = c.resolveRules(alarm.Rules)
+			m.Alarm = &alarm
+		}
+		resolved[i] = m
+	}
+	return resolved
+}
+
+func (c *CustomCalender) resolveRules(rules []config.Rule) []config.Rule {
+	if rules == nil {
+		return nil
+	}
+
+	resolved := make([]config.Rule, len(rules))
+	for i, r := range rules {
+		r.Match = c.resolveRules(r.Match)
+
+		data := append([]string{}, r.Data...)
+		if r.DataFile != "" {
+			data = append(data, c.loadData(r.DataFile, readDataFile)...)
+		}
+		if r.DataUrl != "" {
+			data = append(data, c.loadData(r.DataUrl, fetchDataUrl)...)
+		}
+		r.Data = data
+		resolved[i] = r
+	}
+	return resolved
+}
+
+// loadData loads the data at location, if this fails the last successfully loaded data is used
+func (c *CustomCalender) loadData(location string, load func(string) ([]byte, error)) []string {
+	content, err := load(location)
+	if err == nil {
+		var data []string
+		data, err = parseData(content, location)
+		if err == nil {
+			if c.data == nil {
+				c.data = map[string][]string{}
+			}
+			c.data[location] = data
+			return data
+		}
+	}
+
+	cached, ok := c.data[location]
+	log.Logger.Warn("Error loading rule data", "location", location, "error", err, "using_cache", ok)
+	return cached
+}
+
+func readDataFile(file string) ([]byte, error) {
+	return os.ReadFile(file)
+}
+
+func fetchDataUrl(location string) ([]byte, error) {
+	res, err := http.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, errors.New("Status was not 200 got " + res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// parseData parses a YAML or JSON list when the location has a matching extension,
+// otherwise every non-empty line not starting with # is a value
+func parseData(content []byte, location string) ([]string, error) {
+	if u, err := url.Parse(location); err == nil && u.Scheme != "" {
+		location = u.Path
+	}
+
+	var data []string
+	switch strings.ToLower(path.Ext(location)) {
+	case ".yaml", ".yml":
+		err := yaml.Unmarshal(content, &data)
+		return data, err
+	case ".json":
+		err := json.Unmarshal(content, &data)
+		return data, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		data = append(data, line)
+	}
+	return data, scanner.Err()
+}
@@ -0,0 +1,58 @@
+package ical
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseData(t *testing.T) {
+	data, err := parseData([]byte("# course codes\nWISB123\n\n  INFO456  \n"), "codes.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"WISB123", "INFO456"}, data)
+
+	data, err = parseData([]byte("- WISB123\n- INFO456\n"), "codes.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"WISB123", "INFO456"}, data)
+
+	data, err = parseData([]byte(`["WISB123", "INFO456"]`), "https://example.com/codes.json?v=2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"WISB123", "INFO456"}, data)
+
+	_, err = parseData([]byte(`{"a": 1}`), "codes.json")
+	assert.Error(t, err)
+}
+
+func TestResolveData(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+
+	file := filepath.Join(t.TempDir(), "keywords.txt")
+	require.NoError(t, os.WriteFile(file, []byte("Exam\nDeadline\n"), 0o644))
+
+	cal := &CustomCalender{}
+	source := config.SourceInfo{
+		Rules: []config.Rule{{Check: FilterContainsTerm, Component: "SUMMARY", Data: []string{"Meeting"}, DataFile: file}},
+		Modifiers: []config.Modifier{{
+			Filters: []config.Rule{{Check: FilterContainsTerm, Component: "SUMMARY", DataFile: file}},
+		}},
+	}
+
+	resolved := cal.resolveData(source)
+	assert.Equal(t, []string{"Meeting", "Exam", "Deadline"}, resolved.Rules[0].Data)
+	assert.Equal(t, []string{"Exam", "Deadline"}, resolved.Modifiers[0].Filters[0].Data)
+	// the config itself is left untouched
+	assert.Equal(t, []string{"Meeting"}, source.Rules[0].Data)
+
+	// changes are picked up on the next resolve
+	require.NoError(t, os.WriteFile(file, []byte("Exam\n"), 0o644))
+	assert.Equal(t, []string{"Meeting", "Exam"}, cal.resolveData(source).Rules[0].Data)
+
+	// the last known data is used when the file disappears
+	require.NoError(t, os.Remove(file))
+	assert.Equal(t, []string{"Meeting", "Exam"}, cal.resolveData(source).Rules[0].Data)
+}
@@ -0,0 +1,133 @@
+package ical
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+)
+
+const (
+	// checks if the source in the rule has an event matching the match rules on the same day
+	FilterOnDayOfTerm = "ON_DAY_OF"
+	// checks if the source in the rule has no event matching the match rules on the same day
+	FilterNotOnDayOfTerm = "NOT_ON_DAY_OF"
+)
+
+func init() {
+	RegisterCheck(FilterOnDayOfTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.filterOnDayOf(ctx.Rule, e)
+	}, config.RequireSource)
+	RegisterCheck(FilterNotOnDayOfTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.filterNotOnDayOf(ctx.Rule, e)
+	}, config.RequireSource)
+}
+
+// sibling returns the loaded calendar of the same endpoint with the given name
+func (c *LoadediCal) sibling(name string) *LoadediCal {
+	if c.parent == nil {
+		return nil
+	}
+	for _, other := range c.parent.loaded {
+		if other.Source().Name == name {
+			return other
+		}
+	}
+	return nil
+}
+
+// siblingEvents returns the source in the rule and the events it keeps. The source is filtered
+// first so its own rules are respected, under a circular dependency its unfiltered events are used.
+func (c *LoadediCal) siblingEvents(r *config.Rule) (*LoadediCal, []*ics.VEvent) {
+	other := c.sibling(r.Source)
+	if other == nil {
+		log.Logger.Warn("Source not found", "rule_name", r.Name, "source", r.Source)
+		return nil, nil
+	}
+
+	if other.filtering {
+		log.Logger.Warn("Circular source dependency, using unfiltered events", "rule_name", r.Name, "source", r.Source)
+		return other, other.original
+	}
+	return other, other.FilteredEvents()
+}
+
+// daysOf returns the days on which the source in the rule has an event passing any of
+// the match rules. The source is filtered first so its own rules are respected, the
+// result is cached for the lifetime of the calendar.
+func (c *LoadediCal) daysOf(r *config.Rule) map[string]bool {
+	key := fmt.Sprintf("%s%v", r.Source, r.Match)
+	if days, ok := c.dayCache[key]; ok {
+		return days
+	}
+
+	days := map[string]bool{}
+	other, events := c.siblingEvents(r)
+	if other == nil {
+		return days
+	}
+
+	// positional match rules only look at the events of other
+	probe := &LoadediCal{source: config.SourceInfo{Name: other.source.Name, Rules: r.Match}, events: events, original: events, parent: c.parent}
+	for _, e := range events {
+		if !probe.Check(e) {
+			continue
+		}
+		start, end, err := eventSpan(e)
+		if err != nil {
+			continue
+		}
+		for _, day := range eventDays(start, end) {
+			days[day] = true
+		}
+	}
+
+	if c.dayCache == nil {
+		c.dayCache = map[string]map[string]bool{}
+	}
+	c.dayCache[key] = days
+	return days
+}
+
+// filterOnDayOf checks if the source in the rule has an event matching the match rules on the same day
+func (c *LoadediCal) filterOnDayOf(r *config.Rule, event *ics.VEvent) bool {
+	start, end, err := eventSpan(event)
+	if err != nil {
+		return false
+	}
+
+	days := c.daysOf(r)
+	for _, day := range eventDays(start, end) {
+		if days[day] {
+			return true
+		}
+	}
+	return false
+}
+
+// filterNotOnDayOf checks if the source in the rule has no event matching the match rules on the same day
+func (c *LoadediCal) filterNotOnDayOf(r *config.Rule, event *ics.VEvent) bool {
+	if _, _, err := eventSpan(event); err != nil {
+		return false
+	}
+	return !c.filterOnDayOf(r, event)
+}
+
+// eventDays returns every local day the span touches, an end at midnight does not
+// count towards the next day
+func eventDays(start, end time.Time) []string {
+	start, end = start.In(time.Local), end.In(time.Local)
+	if end.After(start) {
+		end = end.Add(-time.Nanosecond)
+	}
+
+	var days []string
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+	for !day.After(end) {
+		days = append(days, day.Format(time.DateOnly))
+		day = day.AddDate(0, 0, 1)
+	}
+	return days
+}
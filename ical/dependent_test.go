@@ -0,0 +1,73 @@
+package ical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAllDayEvent(id string, summary string, day time.Time) *ics.VEvent {
+	e := ics.NewEvent(id)
+	e.SetAllDayStartAt(day)
+	e.SetSummary(summary)
+	return e
+}
+
+func TestFilterNotOnDayOf(t *testing.T) {
+	monday := time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	holidays := newLoadedCal("holidays",
+		newAllDayEvent("h1", "Public holiday", monday),
+		newAllDayEvent("h2", "Teacher training", tuesday),
+	)
+	school := newLoadedCal("school",
+		newEventWithSpan("l1", monday.Add(9*time.Hour), time.Hour),
+		newEventWithSpan("l2", tuesday.Add(9*time.Hour), time.Hour),
+		newEventWithSpan("l3", tuesday.AddDate(0, 0, 1).Add(9*time.Hour), time.Hour),
+	)
+	school.source.Rules = []config.Rule{{
+		Check:  FilterNotOnDayOfTerm,
+		Source: "holidays",
+		Match:  []config.Rule{{Check: FilterContainsTerm, Component: "SUMMARY", Data: []string{"holiday"}}},
+	}}
+	parent := &CustomCalender{loaded: []*LoadediCal{school, holidays}}
+	school.parent, holidays.parent = parent, parent
+
+	var ids []string
+	for _, e := range school.FilteredEvents() {
+		ids = append(ids, e.Id())
+	}
+	assert.Equal(t, []string{"l2", "l3"}, ids)
+}
+
+func TestFilterOnDayOfUsesFilteredSource(t *testing.T) {
+	monday := time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)
+
+	personal := newLoadedCal("personal",
+		newAllDayEvent("p1", "Vacation", monday),
+		newAllDayEvent("p2", "Birthday", monday.AddDate(0, 0, 1)),
+	)
+	personal.source.Rules = []config.Rule{{Check: FilterEqualsTerm, Component: "SUMMARY", Data: []string{"vacation"}}}
+	work := newLoadedCal("work",
+		newEventWithSpan("w1", monday.Add(9*time.Hour), 8*time.Hour),
+		newEventWithSpan("w2", monday.AddDate(0, 0, 1).Add(9*time.Hour), 8*time.Hour),
+	)
+	parent := &CustomCalender{loaded: []*LoadediCal{work, personal}}
+	work.parent, personal.parent = parent, parent
+
+	rule := config.Rule{Check: FilterOnDayOfTerm, Source: "personal"}
+	assert.True(t, work.filterOnDayOf(&rule, work.original[0]))
+	assert.False(t, work.filterOnDayOf(&rule, work.original[1]))
+	assert.True(t, personal.isFiltered)
+}
+
+func TestEventDays(t *testing.T) {
+	start := time.Date(2024, 3, 4, 22, 0, 0, 0, time.Local)
+	assert.Equal(t, []string{"2024-03-04", "2024-03-05"}, eventDays(start, start.Add(4*time.Hour)))
+	assert.Equal(t, []string{"2024-03-04"}, eventDays(start, start.Add(2*time.Hour)))
+	assert.Equal(t, []string{"2024-03-04"}, eventDays(start, start))
+}
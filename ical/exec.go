@@ -0,0 +1,101 @@
+package ical
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+)
+
+// stderrExcerpt is the number of bytes of stderr kept in errors, the end holds the cause
+const stderrExcerpt = 512
+
+// execWaitDelay is how long output is still read after a command is killed, children that keep
+// its stdout open are not waited for any longer
+const execWaitDelay = time.Second
+
+func init() {
+	RegisterSource(config.SourceExec, loadExec, config.RequireExec)
+}
+
+// loadExec runs the command of the source and parses its output in the format of the source.
+// A failing command reports its exit code and the end of its stderr.
+func loadExec(source config.SourceInfo) ([]*ics.VEvent, error) {
+	def := source.Exec
+	timeout := def.TimeoutOf()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, def.Command, def.Args...)
+	cmd.Dir = def.Dir
+	cmd.Env = execEnv(def.Env)
+	cmd.WaitDelay = execWaitDelay
+	killProcessGroup(cmd)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err := cmd.Run()
+	excerpt := tail(strings.TrimSpace(stderr.String()), stderrExcerpt)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		log.Logger.Error("Command timed out", "source", source.Name, "command", def.Command, "timeout", timeout, "stderr", excerpt)
+		return nil, fmt.Errorf("command %s timed out after %s%s", def.Command, timeout, stderrSuffix(excerpt))
+	case errors.As(err, &exitErr):
+		log.Logger.Error("Command failed", "source", source.Name, "command", def.Command, "exit_code", exitErr.ExitCode(), "stderr", excerpt)
+		return nil, fmt.Errorf("command %s exited with code %d%s", def.Command, exitErr.ExitCode(), stderrSuffix(excerpt))
+	default:
+		return nil, fmt.Errorf("command %s could not be run: %w", def.Command, err)
+	}
+
+	if excerpt != "" {
+		log.Logger.Debug("Command wrote to stderr", "source", source.Name, "command", def.Command, "stderr", excerpt)
+	}
+	return parseFormat(source, &stdout)
+}
+
+// execEnv returns the environment of the merger with env added, sorted so runs are reproducible
+func execEnv(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	vars := os.Environ()
+	for _, key := range keys {
+		vars = append(vars, key+"="+env[key])
+	}
+	return vars
+}
+
+func stderrSuffix(excerpt string) string {
+	if excerpt == "" {
+		return ""
+	}
+	return ": " + excerpt
+}
+
+// tail returns the last n bytes of s, starting at a rune boundary
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[len(s)-n:]
+	for len(s) > 0 && !utf8.RuneStart(s[0]) {
+		s = s[1:]
+	}
+	return "…" + s
+}
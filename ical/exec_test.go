@@ -0,0 +1,80 @@
+package ical
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadExec(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+
+	dir := t.TempDir()
+	calendar := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:scraped\r\nDTSTART:20240304T090000Z\r\n" +
+		"SUMMARY:Scraped\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "calendar.ics"), []byte(calendar), 0o644))
+
+	events, err := loadExec(config.SourceInfo{Name: "Scraper", Type: config.SourceExec, Exec: &config.Exec{
+		Command: "sh",
+		Args:    []string{"-c", `sed "s/Scraped/$PREFIX Scraped/" calendar.ics`},
+		Env:     map[string]string{"PREFIX": "Freshly"},
+		Dir:     dir,
+	}})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "Freshly Scraped", events[0].GetProperty(ics.ComponentPropertySummary).Value)
+	}
+
+	events, err = loadExec(config.SourceInfo{Name: "Cli", Type: config.SourceExec, Format: config.FormatCSV,
+		Csv:  &config.Csv{Columns: config.FieldMapping{Summary: "name", Start: "day"}},
+		Exec: &config.Exec{Command: "printf", Args: []string{`name,day\nDeploy,2024-03-04\n`}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestLoadExecErrors(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+
+	_, err := loadExec(config.SourceInfo{Name: "Failing", Type: config.SourceExec, Exec: &config.Exec{
+		Command: "sh",
+		Args:    []string{"-c", "echo 'progress' >&2; echo 'login required' >&2; exit 3"},
+	}})
+	assert.EqualError(t, err, "command sh exited with code 3: progress\nlogin required")
+
+	_, err = loadExec(config.SourceInfo{Name: "Slow", Type: config.SourceExec, Exec: &config.Exec{
+		Command: "sleep",
+		Args:    []string{"5"},
+		Timeout: "PT1S",
+	}})
+	assert.EqualError(t, err, "command sleep timed out after 1s")
+
+	// children holding on to stdout are killed with the command
+	started := time.Now()
+	_, err = loadExec(config.SourceInfo{Name: "Children", Type: config.SourceExec, Exec: &config.Exec{
+		Command: "sh",
+		Args:    []string{"-c", "sleep 8; echo done"},
+		Timeout: "PT1S",
+	}})
+	assert.EqualError(t, err, "command sh timed out after 1s")
+	assert.Less(t, time.Since(started), 4*time.Second)
+
+	_, err = loadExec(config.SourceInfo{Name: "Missing", Type: config.SourceExec, Exec: &config.Exec{
+		Command: filepath.Join(t.TempDir(), "missing"),
+	}})
+	assert.ErrorContains(t, err, "could not be run")
+}
+
+func TestTail(t *testing.T) {
+	assert.Equal(t, "short", tail("short", 10))
+	assert.Equal(t, "…6789", tail("0123456789", 4))
+	// the excerpt does not start halfway through a rune
+	assert.Equal(t, "…b", tail(strings.Repeat("é", 3)+"b", 2))
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package ical
+
+import "os/exec"
+
+// killProcessGroup is not supported on Windows, only the command itself is killed and
+// WaitDelay stops waiting for its children
+func killProcessGroup(cmd *exec.Cmd) {}
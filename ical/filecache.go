@@ -0,0 +1,78 @@
+package ical
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Fesaa/ical-merger/log"
+)
+
+// filePass counts the passes over the calendars, files are checked for changes once per pass
+var filePass atomic.Uint64
+
+// nextFilePass starts a new pass, files used during it are checked for changes again
+func nextFilePass() {
+	filePass.Add(1)
+}
+
+type cachedFile[T any] struct {
+	modTime time.Time
+	pass    uint64
+	value   T
+}
+
+// fileCache keeps parsed files in memory, reloading them when they change on disk. The values
+// are shared by every caller and must not be changed.
+type fileCache[T any] struct {
+	// kind describes the files in logs
+	kind  string
+	parse func(content []byte, file string) (T, error)
+
+	lock  sync.Mutex
+	files map[string]*cachedFile[T]
+}
+
+func newFileCache[T any](kind string, parse func(content []byte, file string) (T, error)) *fileCache[T] {
+	return &fileCache[T]{kind: kind, parse: parse, files: map[string]*cachedFile[T]{}}
+}
+
+// load returns the parsed file. When it cannot be read or parsed the last version is kept.
+func (c *fileCache[T]) load(file string) T {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	pass := filePass.Load()
+	cached, ok := c.files[file]
+	if ok && cached.pass == pass {
+		return cached.value
+	}
+	if !ok {
+		cached = &cachedFile[T]{}
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		log.Logger.Warn("Error reading "+c.kind, "file", file, "error", err, "using_cache", ok)
+		return cached.value
+	}
+	if ok && info.ModTime().Equal(cached.modTime) {
+		cached.pass = pass
+		return cached.value
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		log.Logger.Warn("Error reading "+c.kind, "file", file, "error", err, "using_cache", ok)
+		return cached.value
+	}
+	value, err := c.parse(content, file)
+	if err != nil {
+		log.Logger.Warn("Error parsing "+c.kind, "file", file, "error", err, "using_cache", ok)
+		return cached.value
+	}
+
+	c.files[file] = &cachedFile[T]{modTime: info.ModTime(), pass: pass, value: value}
+	return value
+}
@@ -0,0 +1,283 @@
+package ical
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+)
+
+// formatParser converts the body loaded for a source into events
+type formatParser func(config.SourceInfo, io.Reader) ([]*ics.VEvent, error)
+
+var formatParsers = map[config.Format]formatParser{
+	config.FormatICS:  parseICS,
+	config.FormatJCal: parseJCal,
+	config.FormatXCal: parseXCal,
+	config.FormatCSV:  parseCSV,
+	config.FormatJSON: parseJSON,
+}
+
+// parseFormat converts the body into events using the parser of the format of the source
+func parseFormat(source config.SourceInfo, r io.Reader) ([]*ics.VEvent, error) {
+	parse, ok := formatParsers[source.FormatOf()]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", source.FormatOf())
+	}
+	return parse(source, r)
+}
+
+func parseICS(_ config.SourceInfo, r io.Reader) ([]*ics.VEvent, error) {
+	cal, err := ics.ParseCalendar(r)
+	if err != nil {
+		return nil, err
+	}
+	return cal.Events(), nil
+}
+
+// parseCSV returns an event for every row, the first row holds the names of the columns.
+// Rows that do not form a valid event are skipped.
+func parseCSV(source config.SourceInfo, r io.Reader) ([]*ics.VEvent, error) {
+	if source.Csv == nil {
+		return nil, errors.New("format csv requires columns")
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if source.Csv.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(source.Csv.Delimiter)
+	}
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range mappedKeys(source.Csv.Columns) {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %q not found", name)
+		}
+	}
+
+	var events []*ics.VEvent
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		e, err := mappedEvent(source, source.Csv.Columns, func(name string) string {
+			if i := columns[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		})
+		if err != nil {
+			log.Logger.Warn("Skipping CSV row", "source", source.Name, "line", line, "error", err)
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// parseJSON returns an event for every element of the array at the events path.
+// Elements that do not form a valid event are skipped.
+func parseJSON(source config.SourceInfo, r io.Reader) ([]*ics.VEvent, error) {
+	if source.Json == nil {
+		return nil, errors.New("format json requires fields")
+	}
+
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	found, ok := jsonPath(doc, source.Json.Events)
+	if !ok {
+		return nil, fmt.Errorf("path %q not found", source.Json.Events)
+	}
+	items, ok := found.([]any)
+	if !ok {
+		return nil, fmt.Errorf("path %q is not an array", source.Json.Events)
+	}
+
+	var events []*ics.VEvent
+	for i, item := range items {
+		e, err := mappedEvent(source, source.Json.Fields, func(path string) string {
+			v, _ := jsonPath(item, path)
+			return jsonString(v)
+		})
+		if err != nil {
+			log.Logger.Warn("Skipping JSON event", "source", source.Name, "index", i, "error", err)
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// mappedEvent builds the event whose fields are looked up with value
+func mappedEvent(source config.SourceInfo, fields config.FieldMapping, value func(string) string) (*ics.VEvent, error) {
+	lookup := func(key string) string {
+		if key == "" {
+			return ""
+		}
+		return value(key)
+	}
+
+	def := config.StaticEvent{
+		Uid:         lookup(fields.Uid),
+		Summary:     lookup(fields.Summary),
+		Description: lookup(fields.Description),
+		Location:    lookup(fields.Location),
+		Duration:    lookup(fields.Duration),
+		RRule:       lookup(fields.RRule),
+	}
+
+	var err error
+	if def.Start, err = normalizeTime(lookup(fields.Start), fields.TimeFormat); err != nil {
+		return nil, err
+	}
+	if end := lookup(fields.End); end != "" {
+		if def.End, err = normalizeTime(end, fields.TimeFormat); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return staticEvent(source, def)
+}
+
+// mappedKeys returns the columns or paths the mapping refers to
+func mappedKeys(fields config.FieldMapping) []string {
+	var keys []string
+	for _, key := range []string{fields.Uid, fields.Summary, fields.Description, fields.Location,
+		fields.Start, fields.End, fields.Duration, fields.RRule} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// icsLayouts are the iCalendar time forms accepted besides those of config.ParseEventTime
+var icsLayouts = []string{"20060102", "20060102T150405", "20060102T150405Z"}
+
+// normalizeTime rewrites a time into a form config.ParseEventTime accepts. Besides those forms
+// it accepts the layout, iCalendar times such as 20240304T090000Z and Unix seconds.
+func normalizeTime(s, layout string) (string, error) {
+	if _, _, err := config.ParseEventTime(s); err == nil {
+		return s, nil
+	}
+
+	if layout != "" {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return eventTime(t, dateLayout(layout)), nil
+		}
+	}
+	for _, l := range icsLayouts {
+		if t, err := time.ParseInLocation(l, s, time.Local); err == nil {
+			if strings.HasSuffix(l, "Z") {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+			}
+			return eventTime(t, dateLayout(l)), nil
+		}
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return eventTime(time.Unix(secs, 0).UTC(), false), nil
+	}
+	return "", fmt.Errorf("invalid date or time %q", s)
+}
+
+// eventTime formats t the way config.ParseEventTime reads it, local times stay floating
+func eventTime(t time.Time, date bool) string {
+	switch {
+	case date:
+		return t.Format(time.DateOnly)
+	case t.Location() == time.Local:
+		return t.Format("2006-01-02T15:04:05")
+	}
+	return t.Format(time.RFC3339)
+}
+
+// dateLayout reports whether the layout holds a date without a time of day
+func dateLayout(layout string) bool {
+	morning := time.Date(2000, 1, 1, 1, 2, 3, 0, time.UTC)
+	return morning.Format(layout) == morning.Add(14*time.Hour).Format(layout)
+}
+
+// jsonPath returns the value at a path such as $.data.items[0].start, an empty path is the value itself
+func jsonPath(v any, path string) (any, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return v, true
+	}
+
+	for _, part := range strings.Split(path, ".") {
+		name, rest, _ := strings.Cut(part, "[")
+		if name != "" {
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if v, ok = m[name]; !ok {
+				return nil, false
+			}
+		}
+
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, false
+			}
+			i, err := strconv.Atoi(index)
+			list, isList := v.([]any)
+			if err != nil || !isList || i < 0 || i >= len(list) {
+				return nil, false
+			}
+			v, rest = list[i], strings.TrimPrefix(after, "[")
+		}
+	}
+	return v, true
+}
+
+// jsonString returns the value as text, objects and arrays are returned as JSON
+func jsonString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
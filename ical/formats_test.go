@@ -0,0 +1,233 @@
+package ical
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func propertyValue(e *ics.VEvent, property ics.ComponentProperty) string {
+	if p := e.GetProperty(property); p != nil {
+		return p.Value
+	}
+	return ""
+}
+
+const testJCal = `["vcalendar",
+  [["version", {}, "text", "2.0"], ["prodid", {}, "text", "-//Example//EN"]],
+  [
+    ["vevent",
+      [
+        ["uid", {}, "text", "meeting@example.com"],
+        ["dtstamp", {}, "date-time", "2024-03-01T08:00:00Z"],
+        ["dtstart", {"tzid": "Europe/Amsterdam"}, "date-time", "2024-03-04T09:00:00"],
+        ["duration", {}, "duration", "PT1H"],
+        ["summary", {}, "text", "Planning, weekly"],
+        ["categories", {}, "text", "Work", "Team"],
+        ["geo", {}, "float", [52.37, 4.89]],
+        ["rrule", {}, "recur", {"freq": "WEEKLY", "byday": ["MO", "WE"], "until": "2024-06-01T00:00:00Z"}]
+      ],
+      [
+        ["valarm", [["action", {}, "text", "DISPLAY"], ["trigger", {}, "duration", "-PT15M"]], []]
+      ]
+    ],
+    ["vevent",
+      [
+        ["uid", {}, "text", "day@example.com"],
+        ["dtstart", {}, "date", "2024-03-05"],
+        ["summary", {}, "text", "Off"]
+      ],
+      []
+    ]
+  ]
+]`
+
+func TestParseJCal(t *testing.T) {
+	events, err := parseJCal(config.SourceInfo{}, strings.NewReader(testJCal))
+	assert.NoError(t, err)
+	if !assert.Len(t, events, 2) {
+		return
+	}
+
+	meeting := events[0]
+	assert.Equal(t, "meeting@example.com", meeting.Id())
+	assert.Equal(t, "20240304T090000", propertyValue(meeting, ics.ComponentPropertyDtStart))
+	assert.Equal(t, []string{"Europe/Amsterdam"}, meeting.GetProperty(ics.ComponentPropertyDtStart).ICalParameters["TZID"])
+	assert.Equal(t, `Planning\, weekly`, propertyValue(meeting, ics.ComponentPropertySummary))
+	assert.Equal(t, "Work,Team", propertyValue(meeting, ics.ComponentPropertyCategories))
+	assert.Equal(t, "52.37;4.89", propertyValue(meeting, ics.ComponentPropertyGeo))
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20240601T000000Z", propertyValue(meeting, ics.ComponentPropertyRrule))
+	assert.Len(t, meeting.Alarms(), 1)
+
+	day := events[1]
+	assert.Equal(t, "20240305", propertyValue(day, ics.ComponentPropertyDtStart))
+	assert.True(t, isAllDay(day))
+
+	_, err = parseJCal(config.SourceInfo{}, strings.NewReader(`["vcalendar", []]`))
+	assert.Error(t, err)
+}
+
+const testXCal = `<?xml version="1.0" encoding="utf-8"?>
+<icalendar xmlns="urn:ietf:params:xml:ns:icalendar-2.0">
+  <vcalendar>
+    <properties>
+      <prodid><text>-//Example//EN</text></prodid>
+      <version><text>2.0</text></version>
+    </properties>
+    <components>
+      <vevent>
+        <properties>
+          <uid><text>meeting@example.com</text></uid>
+          <dtstamp><date-time>2024-03-01T08:00:00Z</date-time></dtstamp>
+          <dtstart>
+            <parameters><tzid><text>Europe/Amsterdam</text></tzid></parameters>
+            <date-time>2024-03-04T09:00:00</date-time>
+          </dtstart>
+          <dtend>
+            <parameters><tzid><text>Europe/Amsterdam</text></tzid></parameters>
+            <date-time>2024-03-04T10:00:00</date-time>
+          </dtend>
+          <summary><text>Planning; weekly</text></summary>
+          <rrule><recur><freq>WEEKLY</freq><count>4</count><byday>MO</byday><byday>WE</byday></recur></rrule>
+        </properties>
+      </vevent>
+      <vevent>
+        <properties>
+          <uid><text>day@example.com</text></uid>
+          <dtstart><date>2024-03-05</date></dtstart>
+          <summary><text>Off</text></summary>
+        </properties>
+      </vevent>
+    </components>
+  </vcalendar>
+</icalendar>`
+
+func TestParseXCal(t *testing.T) {
+	events, err := parseXCal(config.SourceInfo{}, strings.NewReader(testXCal))
+	assert.NoError(t, err)
+	if !assert.Len(t, events, 2) {
+		return
+	}
+
+	meeting := events[0]
+	assert.Equal(t, "meeting@example.com", meeting.Id())
+	assert.Equal(t, "20240304T090000", propertyValue(meeting, ics.ComponentPropertyDtStart))
+	assert.Equal(t, "20240304T100000", propertyValue(meeting, ics.ComponentPropertyDtEnd))
+	assert.Equal(t, []string{"Europe/Amsterdam"}, meeting.GetProperty(ics.ComponentPropertyDtEnd).ICalParameters["TZID"])
+	assert.Equal(t, `Planning\; weekly`, propertyValue(meeting, ics.ComponentPropertySummary))
+	assert.Equal(t, "FREQ=WEEKLY;COUNT=4;BYDAY=MO,WE", propertyValue(meeting, ics.ComponentPropertyRrule))
+
+	assert.True(t, isAllDay(events[1]))
+
+	_, err = parseXCal(config.SourceInfo{}, strings.NewReader(`<calendar/>`))
+	assert.Error(t, err)
+}
+
+func TestParseCSV(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	source := config.SourceInfo{Name: "Rooms", Format: config.FormatCSV, Csv: &config.Csv{
+		Delimiter: ";",
+		Columns: config.FieldMapping{
+			Uid:      "Id",
+			Summary:  "Title",
+			Location: "Room",
+			Start:    "From",
+			End:      "Until",
+		},
+	}}
+	body := "Id;Title;Room;From;Until\n" +
+		"1;Review;Room 1;2024-03-04T09:00;2024-03-04T10:00\n" +
+		"2;Offsite;;2024-03-05;2024-03-06\n" +
+		"3;Broken;Room 2;tomorrow;\n" +
+		"4;Standup;Room 3;20240304T080000Z;20240304T081500Z\n"
+
+	events, err := parseCSV(source, strings.NewReader(body))
+	assert.NoError(t, err)
+	if !assert.Len(t, events, 3) {
+		return
+	}
+
+	assert.Equal(t, "1", events[0].Id())
+	assert.Equal(t, "Room 1", propertyValue(events[0], ics.ComponentPropertyLocation))
+	assert.Equal(t, "20240304T090000", propertyValue(events[0], ics.ComponentPropertyDtStart))
+	assert.Equal(t, "20240305", propertyValue(events[1], ics.ComponentPropertyDtStart))
+	assert.Equal(t, "20240307", propertyValue(events[1], ics.ComponentPropertyDtEnd))
+	assert.Equal(t, "20240304T080000Z", propertyValue(events[2], ics.ComponentPropertyDtStart))
+
+	source.Csv.Columns.Description = "Notes"
+	_, err = parseCSV(source, strings.NewReader(body))
+	assert.EqualError(t, err, `column "Notes" not found`)
+}
+
+func TestParseJSON(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	source := config.SourceInfo{Name: "Tickets", Format: config.FormatJSON, Json: &config.Json{
+		Events: "$.data.items",
+		Fields: config.FieldMapping{
+			Summary:    "title",
+			Start:      "when.start",
+			End:        "when.end",
+			Location:   "rooms[0]",
+			TimeFormat: "02/01/2006 15:04",
+		},
+	}}
+	body := `{"data": {"items": [
+		{"title": "Deploy", "when": {"start": "04/03/2024 09:00", "end": "04/03/2024 09:30"}, "rooms": ["Ops"]},
+		{"title": "Release", "when": {"start": 1709546400}},
+		{"title": "No start"}
+	]}}`
+
+	events, err := parseJSON(source, strings.NewReader(body))
+	assert.NoError(t, err)
+	if !assert.Len(t, events, 2) {
+		return
+	}
+
+	assert.Equal(t, "Deploy", propertyValue(events[0], ics.ComponentPropertySummary))
+	assert.Equal(t, "Ops", propertyValue(events[0], ics.ComponentPropertyLocation))
+	assert.Equal(t, "20240304T090000", propertyValue(events[0], ics.ComponentPropertyDtStart))
+	assert.Equal(t, "20240304T093000", propertyValue(events[0], ics.ComponentPropertyDtEnd))
+	assert.Equal(t, "20240304T100000Z", propertyValue(events[1], ics.ComponentPropertyDtStart))
+
+	source.Json.Events = "data"
+	_, err = parseJSON(source, strings.NewReader(body))
+	assert.EqualError(t, err, `path "data" is not an array`)
+}
+
+func TestJSONPath(t *testing.T) {
+	doc := map[string]any{"a": []any{map[string]any{"b": "c"}}}
+	for path, want := range map[string]any{
+		"$.a[0].b": "c",
+		"a[0].b":   "c",
+		"$.a[1]":   nil,
+		"a.b":      nil,
+	} {
+		v, _ := jsonPath(doc, path)
+		assert.Equal(t, want, v, path)
+	}
+	v, ok := jsonPath(doc, "$")
+	assert.True(t, ok)
+	assert.Equal(t, doc, v)
+}
+
+func TestNewLoadediCalFormat(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("title,start\nLaunch,2024-03-04\n"))
+	}))
+	defer server.Close()
+
+	cal, err := NewLoadediCal(config.SourceInfo{Name: "Launches", Url: server.URL, Format: config.FormatCSV, Csv: &config.Csv{
+		Columns: config.FieldMapping{Summary: "title", Start: "start"},
+	}})
+	assert.NoError(t, err)
+	if assert.Len(t, cal.Events(), 1) {
+		assert.Equal(t, "Launch", propertyValue(cal.Events()[0], ics.ComponentPropertySummary))
+	}
+}
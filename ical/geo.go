@@ -0,0 +1,141 @@
+package ical
+
+import (
+	"math"
+	"strings"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// checks if the event takes place within the radius around the point in the rule
+	FilterWithinRadiusTerm = "WITHIN_RADIUS"
+	// checks if the event takes place within the bounding box in the rule
+	FilterWithinBoxTerm = "WITHIN_BOX"
+)
+
+const earthRadiusKm = 6371.0
+
+func init() {
+	RegisterCheck(FilterWithinRadiusTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.filterWithinRadius(ctx.Rule, e)
+	}, config.RequireGeoRadius)
+	RegisterCheck(FilterWithinBoxTerm, func(e *ics.VEvent, ctx RuleContext) bool {
+		return ctx.Calendar.filterWithinBox(ctx.Rule, e)
+	}, config.RequireGeoBox)
+}
+
+// filterWithinRadius checks if the event takes place within the radius around the point in the rule
+func (c *LoadediCal) filterWithinRadius(r *config.Rule, event *ics.VEvent) bool {
+	lat, lon, ok := eventCoordinates(r, event)
+	if !ok {
+		return false
+	}
+
+	pLat, pLon, err := config.ParseCoordinates(r.Geo.Point)
+	if err != nil {
+		return false
+	}
+	return haversine(lat, lon, pLat, pLon) <= r.Geo.Radius
+}
+
+// filterWithinBox checks if the event takes place within the bounding box in the rule
+func (c *LoadediCal) filterWithinBox(r *config.Rule, event *ics.VEvent) bool {
+	lat, lon, ok := eventCoordinates(r, event)
+	if !ok {
+		return false
+	}
+
+	box, err := config.ParseBox(r.Geo.Box)
+	if err != nil {
+		return false
+	}
+
+	south, west, north, east := box[0], box[1], box[2], box[3]
+	if lat < south || lat > north {
+		return false
+	}
+	// a box crossing the antimeridian has its west edge east of its east edge
+	if west <= east {
+		return lon >= west && lon <= east
+	}
+	return lon >= west || lon <= east
+}
+
+// eventCoordinates returns the GEO of the event, falling back to looking up the
+// LOCATION in the gazetteer of the rule
+func eventCoordinates(r *config.Rule, event *ics.VEvent) (float64, float64, bool) {
+	if p := event.GetProperty(ics.ComponentPropertyGeo); p != nil {
+		if lat, lon, err := config.ParseCoordinates(p.Value); err == nil {
+			return lat, lon, true
+		}
+	}
+
+	p := event.GetProperty(ics.ComponentPropertyLocation)
+	if p == nil || r.Geo == nil || r.Geo.Gazetteer == "" {
+		return 0, 0, false
+	}
+
+	places := gazetteers.load(r.Geo.Gazetteer)
+	location := r.Transform(p.Value)
+
+	// prefer an exact match, otherwise the longest place name contained in the location
+	var (
+		best    string
+		bestLen int
+	)
+	for name := range places {
+		t := r.Transform(name)
+		if t == location {
+			best = name
+			break
+		}
+		if t == "" || !strings.Contains(location, t) {
+			continue
+		}
+		if len(t) > bestLen || (len(t) == bestLen && name < best) {
+			best, bestLen = name, len(t)
+		}
+	}
+	if best == "" {
+		return 0, 0, false
+	}
+
+	coords := places[best]
+	return coords[0], coords[1], true
+}
+
+// haversine returns the great-circle distance in kilometres between two points
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// gazetteers keeps the gazetteers of location checks in memory
+var gazetteers = newFileCache("gazetteer", parseGazetteer)
+
+// parseGazetteer parses a YAML or JSON mapping of place names to "lat,lon" coordinates,
+// invalid entries are skipped
+func parseGazetteer(content []byte, file string) (map[string][2]float64, error) {
+	var raw map[string]string
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	places := make(map[string][2]float64, len(raw))
+	for name, coords := range raw {
+		lat, lon, err := config.ParseCoordinates(coords)
+		if err != nil {
+			log.Logger.Warn("Invalid gazetteer entry", "file", file, "place", name, "error", err)
+			continue
+		}
+		places[name] = [2]float64{lat, lon}
+	}
+	return places, nil
+}
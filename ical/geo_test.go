@@ -0,0 +1,55 @@
+package ical
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterWithinRadius(t *testing.T) {
+	ical := &LoadediCal{}
+	// Utrecht, with a 10km radius
+	rule := config.Rule{Check: FilterWithinRadiusTerm, Geo: &config.Geo{Point: "52.0907,5.1214", Radius: 10}}
+
+	assert.True(t, ical.filterWithinRadius(&rule, newEventWithProperty(ics.ComponentPropertyGeo, "52.0860;5.1770")))
+	// Amsterdam is about 35km away
+	assert.False(t, ical.filterWithinRadius(&rule, newEventWithProperty(ics.ComponentPropertyGeo, "52.3676;4.9041")))
+	assert.False(t, ical.filterWithinRadius(&rule, newEventWithProperty(ics.ComponentPropertySummary, "No location")))
+}
+
+func TestFilterWithinBox(t *testing.T) {
+	ical := &LoadediCal{}
+	rule := config.Rule{Check: FilterWithinBoxTerm, Geo: &config.Geo{Box: "50.75,3.36,53.55,7.23"}}
+
+	assert.True(t, ical.filterWithinBox(&rule, newEventWithProperty(ics.ComponentPropertyGeo, "52.0907;5.1214")))
+	assert.False(t, ical.filterWithinBox(&rule, newEventWithProperty(ics.ComponentPropertyGeo, "48.8566;2.3522")))
+
+	// crossing the antimeridian
+	rule.Geo.Box = "-50,170,-30,-170"
+	assert.True(t, ical.filterWithinBox(&rule, newEventWithProperty(ics.ComponentPropertyGeo, "-41.28;174.77")))
+	assert.False(t, ical.filterWithinBox(&rule, newEventWithProperty(ics.ComponentPropertyGeo, "-41.28;150")))
+}
+
+func TestGazetteer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "places.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("Utrecht: \"52.0907,5.1214\"\nUtrecht Science Park: \"52.0860,5.1770\"\nAmsterdam: \"52.3676,4.9041\"\n"), 0o644))
+
+	ical := &LoadediCal{}
+	rule := config.Rule{Check: FilterWithinRadiusTerm, Geo: &config.Geo{Point: "52.0860,5.1770", Radius: 1, Gazetteer: file}}
+
+	assert.True(t, ical.filterWithinRadius(&rule, newEventWithProperty(ics.ComponentPropertyLocation, "utrecht science park")))
+	assert.True(t, ical.filterWithinRadius(&rule, newEventWithProperty(ics.ComponentPropertyLocation, "Room 4, Utrecht Science Park")))
+	assert.False(t, ical.filterWithinRadius(&rule, newEventWithProperty(ics.ComponentPropertyLocation, "Utrecht Centraal")))
+	assert.False(t, ical.filterWithinRadius(&rule, newEventWithProperty(ics.ComponentPropertyLocation, "Amsterdam")))
+	assert.False(t, ical.filterWithinRadius(&rule, newEventWithProperty(ics.ComponentPropertyLocation, "Unknown place")))
+}
+
+func TestHaversine(t *testing.T) {
+	assert.InDelta(t, 343.5, haversine(51.5074, -0.1278, 48.8566, 2.3522), 1)
+	assert.InDelta(t, 0, haversine(10, 10, 10, 10), 0.0001)
+}
@@ -0,0 +1,327 @@
+package ical
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+)
+
+func init() {
+	RegisterSource(config.SourceHolidays, loadHolidays, config.RequireHolidays, requireKnownCountries)
+}
+
+// observance decides what happens to a holiday falling in the weekend
+type observance int
+
+const (
+	observeNone observance = iota
+	// observeNearestWeekday adds an observed day on the Friday before a Saturday or the Monday after a Sunday
+	observeNearestWeekday
+	// observeSubstitute adds a substitute day on the first following weekday that is not a holiday yet
+	observeSubstitute
+	// moveSundayBack moves the holiday to the Saturday before when it falls on a Sunday
+	moveSundayBack
+)
+
+type holidayRule struct {
+	name string
+	date func(year int) time.Time
+	// regions limits the holiday to these regions of the country, it applies to the whole country when empty
+	regions []string
+	// from and to limit the holiday to these years, 0 is unbounded
+	from, to int
+	observe  observance
+}
+
+func (r holidayRule) appliesTo(region string, year int) bool {
+	if (r.from != 0 && year < r.from) || (r.to != 0 && year > r.to) {
+		return false
+	}
+	return len(r.regions) == 0 || slices.Contains(r.regions, region)
+}
+
+func fixed(month time.Month, day int) func(int) time.Time {
+	return func(year int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+}
+
+func easterOffset(days int) func(int) time.Time {
+	return func(year int) time.Time {
+		return easter(year).AddDate(0, 0, days)
+	}
+}
+
+// nthWeekday returns the nth weekday of the month, counting from the end when n is negative
+func nthWeekday(month time.Month, weekday time.Weekday, n int) func(int) time.Time {
+	return func(year int) time.Time {
+		if n < 0 {
+			last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.Local)
+			back := (int(last.Weekday()) - int(weekday) + 7) % 7
+			return last.AddDate(0, 0, -back+(n+1)*7)
+		}
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+		ahead := (int(weekday) - int(first.Weekday()) + 7) % 7
+		return first.AddDate(0, 0, ahead+(n-1)*7)
+	}
+}
+
+// weekdayBefore returns the last weekday strictly before the day of the month
+func weekdayBefore(month time.Month, day int, weekday time.Weekday) func(int) time.Time {
+	return func(year int) time.Time {
+		t := time.Date(year, month, day, 0, 0, 0, 0, time.Local).AddDate(0, 0, -1)
+		back := (int(t.Weekday()) - int(weekday) + 7) % 7
+		return t.AddDate(0, 0, -back)
+	}
+}
+
+// easter returns Easter Sunday of the Gregorian calendar, using the anonymous Gregorian algorithm
+func easter(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+}
+
+// holidayRegions holds the regions that can be selected per country
+var holidayRegions = map[string][]string{
+	"BE": nil,
+	"DE": {"BW", "BY", "BE", "BB", "HB", "HH", "HE", "MV", "NI", "NW", "RP", "SL", "SN", "ST", "SH", "TH"},
+	"FR": {"57", "67", "68"},
+	"GB": {"ENG", "WLS", "SCT", "NIR"},
+	"NL": nil,
+	"US": nil,
+}
+
+// holidayRules holds the regular public holidays per country. One-off holidays, such as
+// those for coronations or jubilees, are not included.
+var holidayRules = map[string][]holidayRule{
+	"BE": {
+		{name: "New Year's Day", date: fixed(time.January, 1)},
+		{name: "Easter Sunday", date: easterOffset(0)},
+		{name: "Easter Monday", date: easterOffset(1)},
+		{name: "Labour Day", date: fixed(time.May, 1)},
+		{name: "Ascension Day", date: easterOffset(39)},
+		{name: "Whit Sunday", date: easterOffset(49)},
+		{name: "Whit Monday", date: easterOffset(50)},
+		{name: "Belgian National Day", date: fixed(time.July, 21)},
+		{name: "Assumption Day", date: fixed(time.August, 15)},
+		{name: "All Saints' Day", date: fixed(time.November, 1)},
+		{name: "Armistice Day", date: fixed(time.November, 11)},
+		{name: "Christmas Day", date: fixed(time.December, 25)},
+	},
+	"DE": {
+		{name: "New Year's Day", date: fixed(time.January, 1)},
+		{name: "Epiphany", date: fixed(time.January, 6), regions: []string{"BW", "BY", "ST"}},
+		{name: "International Women's Day", date: fixed(time.March, 8), regions: []string{"BE"}, from: 2019},
+		{name: "International Women's Day", date: fixed(time.March, 8), regions: []string{"MV"}, from: 2023},
+		{name: "Good Friday", date: easterOffset(-2)},
+		{name: "Easter Monday", date: easterOffset(1)},
+		{name: "Labour Day", date: fixed(time.May, 1)},
+		{name: "Ascension Day", date: easterOffset(39)},
+		{name: "Whit Monday", date: easterOffset(50)},
+		{name: "Corpus Christi", date: easterOffset(60), regions: []string{"BW", "BY", "HE", "NW", "RP", "SL"}},
+		{name: "Assumption Day", date: fixed(time.August, 15), regions: []string{"SL"}},
+		{name: "World Children's Day", date: fixed(time.September, 20), regions: []string{"TH"}, from: 2019},
+		{name: "German Unity Day", date: fixed(time.October, 3), from: 1990},
+		{name: "Reformation Day", date: fixed(time.October, 31), regions: []string{"BB", "MV", "SN", "ST", "TH"}},
+		{name: "Reformation Day", date: fixed(time.October, 31), regions: []string{"HB", "HH", "NI", "SH"}, from: 2018},
+		{name: "All Saints' Day", date: fixed(time.November, 1), regions: []string{"BW", "BY", "NW", "RP", "SL"}},
+		{name: "Day of Repentance and Prayer", date: weekdayBefore(time.November, 23, time.Wednesday), regions: []string{"SN"}},
+		{name: "Christmas Day", date: fixed(time.December, 25)},
+		{name: "Second Day of Christmas", date: fixed(time.December, 26)},
+	},
+	"FR": {
+		{name: "New Year's Day", date: fixed(time.January, 1)},
+		{name: "Good Friday", date: easterOffset(-2), regions: []string{"57", "67", "68"}},
+		{name: "Easter Monday", date: easterOffset(1)},
+		{name: "Labour Day", date: fixed(time.May, 1)},
+		{name: "Victory in Europe Day", date: fixed(time.May, 8)},
+		{name: "Ascension Day", date: easterOffset(39)},
+		{name: "Whit Monday", date: easterOffset(50)},
+		{name: "Bastille Day", date: fixed(time.July, 14)},
+		{name: "Assumption Day", date: fixed(time.August, 15)},
+		{name: "All Saints' Day", date: fixed(time.November, 1)},
+		{name: "Armistice Day", date: fixed(time.November, 11)},
+		{name: "Christmas Day", date: fixed(time.December, 25)},
+		{name: "St Stephen's Day", date: fixed(time.December, 26), regions: []string{"57", "67", "68"}},
+	},
+	"GB": {
+		{name: "New Year's Day", date: fixed(time.January, 1), observe: observeSubstitute},
+		{name: "2nd January", date: fixed(time.January, 2), regions: []string{"SCT"}, observe: observeSubstitute},
+		{name: "St Patrick's Day", date: fixed(time.March, 17), regions: []string{"NIR"}, observe: observeSubstitute},
+		{name: "Good Friday", date: easterOffset(-2)},
+		{name: "Easter Monday", date: easterOffset(1), regions: []string{"ENG", "WLS", "NIR"}},
+		{name: "Early May bank holiday", date: nthWeekday(time.May, time.Monday, 1)},
+		{name: "Spring bank holiday", date: nthWeekday(time.May, time.Monday, -1)},
+		{name: "Battle of the Boyne", date: fixed(time.July, 12), regions: []string{"NIR"}, observe: observeSubstitute},
+		{name: "Summer bank holiday", date: nthWeekday(time.August, time.Monday, 1), regions: []string{"SCT"}},
+		{name: "Summer bank holiday", date: nthWeekday(time.August, time.Monday, -1), regions: []string{"ENG", "WLS", "NIR"}},
+		{name: "St Andrew's Day", date: fixed(time.November, 30), regions: []string{"SCT"}, observe: observeSubstitute},
+		{name: "Christmas Day", date: fixed(time.December, 25), observe: observeSubstitute},
+		{name: "Boxing Day", date: fixed(time.December, 26), observe: observeSubstitute},
+	},
+	"NL": {
+		{name: "New Year's Day", date: fixed(time.January, 1)},
+		{name: "Good Friday", date: easterOffset(-2)},
+		{name: "Easter Sunday", date: easterOffset(0)},
+		{name: "Easter Monday", date: easterOffset(1)},
+		{name: "King's Day", date: fixed(time.April, 27), from: 2014, observe: moveSundayBack},
+		{name: "Liberation Day", date: fixed(time.May, 5)},
+		{name: "Ascension Day", date: easterOffset(39)},
+		{name: "Whit Sunday", date: easterOffset(49)},
+		{name: "Whit Monday", date: easterOffset(50)},
+		{name: "Christmas Day", date: fixed(time.December, 25)},
+		{name: "Second Day of Christmas", date: fixed(time.December, 26)},
+	},
+	"US": {
+		{name: "New Year's Day", date: fixed(time.January, 1), observe: observeNearestWeekday},
+		{name: "Martin Luther King Jr. Day", date: nthWeekday(time.January, time.Monday, 3), from: 1986},
+		{name: "Washington's Birthday", date: nthWeekday(time.February, time.Monday, 3)},
+		{name: "Memorial Day", date: nthWeekday(time.May, time.Monday, -1)},
+		{name: "Juneteenth National Independence Day", date: fixed(time.June, 19), from: 2021, observe: observeNearestWeekday},
+		{name: "Independence Day", date: fixed(time.July, 4), observe: observeNearestWeekday},
+		{name: "Labor Day", date: nthWeekday(time.September, time.Monday, 1)},
+		{name: "Columbus Day", date: nthWeekday(time.October, time.Monday, 2)},
+		{name: "Veterans Day", date: fixed(time.November, 11), observe: observeNearestWeekday},
+		{name: "Thanksgiving Day", date: nthWeekday(time.November, time.Thursday, 4)},
+		{name: "Christmas Day", date: fixed(time.December, 25), observe: observeNearestWeekday},
+	},
+}
+
+// splitCountry splits a code such as DE-BY in its country and region
+func splitCountry(code string) (string, string) {
+	country, region, _ := strings.Cut(strings.ToUpper(code), "-")
+	return country, region
+}
+
+// requireKnownCountries ensures every country and region of a holidays source is available
+func requireKnownCountries(s *config.SourceInfo) config.ValidationErrors {
+	if s.Holidays == nil {
+		return nil
+	}
+	var errs config.ValidationErrors
+	for _, code := range s.Holidays.Countries {
+		country, region := splitCountry(code)
+		regions, ok := holidayRegions[country]
+		switch {
+		case !ok:
+			errs = append(errs, config.FieldError("holidays", "country %q is not available", code)...)
+		case region != "" && !slices.Contains(regions, region):
+			errs = append(errs, config.FieldError("holidays", "region %q is not available", code)...)
+		}
+	}
+	return errs
+}
+
+type holiday struct {
+	name string
+	date time.Time
+}
+
+// holidaysOf returns the holidays of the country and region in the year, including
+// observed and substitute days
+func holidaysOf(country, region string, year int) []holiday {
+	var days []holiday
+	var observed []holidayRule
+	for _, rule := range holidayRules[country] {
+		if !rule.appliesTo(region, year) {
+			continue
+		}
+		date := rule.date(year)
+		if rule.observe == moveSundayBack && date.Weekday() == time.Sunday {
+			date = date.AddDate(0, 0, -1)
+		}
+		days = append(days, holiday{name: rule.name, date: date})
+		if rule.observe == observeNearestWeekday || rule.observe == observeSubstitute {
+			observed = append(observed, rule)
+		}
+	}
+	sort.SliceStable(days, func(i, j int) bool {
+		return days[i].date.Before(days[j].date)
+	})
+
+	taken := map[string]bool{}
+	for _, day := range days {
+		taken[day.date.Format(time.DateOnly)] = true
+	}
+
+	sort.SliceStable(observed, func(i, j int) bool {
+		return observed[i].date(year).Before(observed[j].date(year))
+	})
+	for _, rule := range observed {
+		date := rule.date(year)
+		weekday := date.Weekday()
+		if weekday != time.Saturday && weekday != time.Sunday {
+			continue
+		}
+
+		switch rule.observe {
+		case observeNearestWeekday:
+			if weekday == time.Saturday {
+				date = date.AddDate(0, 0, -1)
+			} else {
+				date = date.AddDate(0, 0, 1)
+			}
+			days = append(days, holiday{name: rule.name + " (observed)", date: date})
+		case observeSubstitute:
+			for date.Weekday() == time.Saturday || date.Weekday() == time.Sunday || taken[date.Format(time.DateOnly)] {
+				date = date.AddDate(0, 0, 1)
+			}
+			taken[date.Format(time.DateOnly)] = true
+			days = append(days, holiday{name: rule.name + " (substitute day)", date: date})
+		}
+	}
+
+	sort.SliceStable(days, func(i, j int) bool {
+		return days[i].date.Before(days[j].date)
+	})
+	return days
+}
+
+// loadHolidays returns the public holidays of the countries and years of the source as
+// transparent all-day events. With more than one country the code is added to the summary.
+func loadHolidays(source config.SourceInfo) ([]*ics.VEvent, error) {
+	years := source.Holidays.Years
+	if len(years) == 0 {
+		year := now().Year()
+		years = []int{year - 1, year, year + 1}
+	}
+
+	var events []*ics.VEvent
+	for _, code := range source.Holidays.Countries {
+		country, region := splitCountry(code)
+		if _, ok := holidayRules[country]; !ok {
+			return nil, fmt.Errorf("country %q is not available", code)
+		}
+
+		for _, year := range years {
+			for _, day := range holidaysOf(country, region, year) {
+				summary := day.name
+				if len(source.Holidays.Countries) > 1 {
+					summary = fmt.Sprintf("%s (%s)", day.name, strings.ToUpper(code))
+				}
+
+				e := newGeneratedEvent(generatedUid(source, code, day.date.Format(time.DateOnly), day.name), summary, day.date)
+				e.SetAllDayStartAt(day.date)
+				e.SetAllDayEndAt(day.date.AddDate(0, 0, 1))
+				e.SetTimeTransparency(ics.TransparencyTransparent)
+				events = append(events, e)
+			}
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,128 @@
+package ical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEaster(t *testing.T) {
+	for year, want := range map[int]string{
+		1961: "1961-04-02",
+		2008: "2008-03-23",
+		2019: "2019-04-21",
+		2024: "2024-03-31",
+		2025: "2025-04-20",
+		2038: "2038-04-25",
+	} {
+		assert.Equal(t, want, easter(year).Format(time.DateOnly), year)
+	}
+}
+
+// holidayDates maps the holidays of the region in the year by name to their date
+func holidayDates(country, region string, year int) map[string]string {
+	dates := map[string]string{}
+	for _, day := range holidaysOf(country, region, year) {
+		dates[day.name] = day.date.Format(time.DateOnly)
+	}
+	return dates
+}
+
+func TestHolidaysKnownDates(t *testing.T) {
+	for _, tc := range []struct {
+		country, region string
+		year            int
+		want            map[string]string
+	}{
+		{"US", "", 2024, map[string]string{
+			"Martin Luther King Jr. Day": "2024-01-15",
+			"Washington's Birthday":      "2024-02-19",
+			"Memorial Day":               "2024-05-27",
+			"Labor Day":                  "2024-09-02",
+			"Columbus Day":               "2024-10-14",
+			"Thanksgiving Day":           "2024-11-28",
+		}},
+		{"US", "", 2022, map[string]string{
+			"New Year's Day (observed)":                       "2021-12-31",
+			"Juneteenth National Independence Day (observed)": "2022-06-20",
+			"Christmas Day (observed)":                        "2022-12-26",
+		}},
+		{"GB", "ENG", 2021, map[string]string{
+			"Christmas Day (substitute day)": "2021-12-27",
+			"Boxing Day (substitute day)":    "2021-12-28",
+			"Summer bank holiday":            "2021-08-30",
+			"Easter Monday":                  "2021-04-05",
+		}},
+		{"GB", "ENG", 2022, map[string]string{
+			"Christmas Day (substitute day)": "2022-12-27",
+			"Spring bank holiday":            "2022-05-30",
+		}},
+		{"GB", "SCT", 2024, map[string]string{
+			"2nd January":         "2024-01-02",
+			"Summer bank holiday": "2024-08-05",
+		}},
+		{"NL", "", 2025, map[string]string{
+			"King's Day":    "2025-04-26",
+			"Ascension Day": "2025-05-29",
+			"Whit Monday":   "2025-06-09",
+		}},
+		{"DE", "BY", 2024, map[string]string{
+			"Epiphany":       "2024-01-06",
+			"Corpus Christi": "2024-05-30",
+			"Good Friday":    "2024-03-29",
+		}},
+		{"DE", "SN", 2024, map[string]string{
+			"Day of Repentance and Prayer": "2024-11-20",
+			"Reformation Day":              "2024-10-31",
+		}},
+		{"BE", "", 2024, map[string]string{
+			"Whit Monday":          "2024-05-20",
+			"Belgian National Day": "2024-07-21",
+		}},
+	} {
+		got := holidayDates(tc.country, tc.region, tc.year)
+		for name, date := range tc.want {
+			assert.Equal(t, date, got[name], "%s-%s %d %s", tc.country, tc.region, tc.year, name)
+		}
+	}
+}
+
+func TestHolidaysRegions(t *testing.T) {
+	national := holidayDates("DE", "", 2024)
+	assert.NotContains(t, national, "Corpus Christi")
+	assert.Contains(t, national, "German Unity Day")
+
+	assert.NotContains(t, holidayDates("DE", "HH", 2017), "Reformation Day")
+	assert.Contains(t, holidayDates("DE", "HH", 2018), "Reformation Day")
+	assert.NotContains(t, holidayDates("GB", "SCT", 2024), "Easter Monday")
+	assert.NotContains(t, holidayDates("US", "", 2020), "Juneteenth National Independence Day")
+
+	// weekday holidays are not observed again
+	assert.NotContains(t, holidayDates("US", "", 2024), "Independence Day (observed)")
+}
+
+func TestHolidaysSource(t *testing.T) {
+	source := config.SourceInfo{Name: "holidays", Type: config.SourceHolidays, Holidays: &config.Holidays{
+		Countries: []string{"nl", "BE"},
+		Years:     []int{2024},
+	}}
+	assert.Empty(t, requireKnownCountries(&source))
+
+	cal, err := NewLoadediCal(source)
+	assert.NoError(t, err)
+	assert.Len(t, cal.Events(), 11+12)
+
+	e := cal.Events()[0]
+	assert.Equal(t, "New Year's Day (NL)", e.GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Equal(t, "20240101", e.GetProperty(ics.ComponentPropertyDtStart).Value)
+	assert.Equal(t, "TRANSPARENT", e.GetProperty(ics.ComponentPropertyTransp).Value)
+
+	source.Holidays.Countries = []string{"XX", "DE-ZZ", "GB-SCT"}
+	assert.Equal(t, config.ValidationErrors{
+		{Field: "holidays", Msg: `country "XX" is not available`},
+		{Field: "holidays", Msg: `region "DE-ZZ" is not available`},
+	}, requireKnownCountries(&source))
+}
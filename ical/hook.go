@@ -0,0 +1,316 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+)
+
+func init() {
+	RegisterAction(config.HOOK, deferStructural, config.RequireModifierHook)
+	structuralActions[config.HOOK] = runHook
+}
+
+// hookRequest is written to the hook as a single line of JSON, one per batch of events
+type hookRequest struct {
+	Modifier string `json:"modifier"`
+	Calendar []any  `json:"calendar"`
+}
+
+// hookResponse is the single line of JSON the hook replies with. The events in the calendar
+// replace the batch, a non-empty error keeps the batch unchanged.
+type hookResponse struct {
+	Calendar json.RawMessage `json:"calendar"`
+	Error    string          `json:"error"`
+}
+
+// runHook sends the events in group to the hook of the modifier and replaces them by the
+// events it returns. When the hook fails the events are kept unchanged.
+func runHook(events, group []*ics.VEvent, m *config.Modifier) []*ics.VEvent {
+	present := make(map[*ics.VEvent]bool, len(events))
+	for _, e := range events {
+		present[e] = true
+	}
+	batch := make([]*ics.VEvent, 0, len(group))
+	for _, e := range group {
+		if present[e] {
+			batch = append(batch, e)
+		}
+	}
+	if len(batch) == 0 {
+		return events
+	}
+
+	replaced, err := callHook(m, batch)
+	if err != nil {
+		log.Logger.Warn("Hook failed, keeping events unchanged", "modifier_name", m.Name, "command", m.Hook.Command, "error", err)
+		return events
+	}
+	log.Logger.Debug("Hook modified events", "modifier_name", m.Name, "sent", len(batch), "received", len(replaced))
+
+	// the returned events take the place of the first event of the batch
+	inBatch := make(map[*ics.VEvent]bool, len(batch))
+	for _, e := range batch {
+		inBatch[e] = true
+	}
+	result := make([]*ics.VEvent, 0, len(events)-len(batch)+len(replaced))
+	for _, e := range events {
+		if !inBatch[e] {
+			result = append(result, e)
+			continue
+		}
+		if replaced != nil {
+			result = append(result, replaced...)
+			replaced = nil
+		}
+	}
+	return result
+}
+
+// callHook sends the batch to the running hook, starting it when needed, and returns the events it replies with
+func callHook(m *config.Modifier, batch []*ics.VEvent) ([]*ics.VEvent, error) {
+	request, err := json.Marshal(hookRequest{Modifier: m.Name, Calendar: jcalCalendar(batch)})
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := startHook(m.Hook)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := p.call(append(request, '\n'), m.Hook.TimeoutOf())
+	if err != nil {
+		// the state of the hook is unknown, the next batch starts a new one
+		stopHook(m.Hook, p)
+		return nil, err
+	}
+
+	var response hookResponse
+	if err := json.Unmarshal(reply, &response); err != nil {
+		return nil, fmt.Errorf("invalid reply: %w", err)
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+	if len(response.Calendar) == 0 {
+		return nil, errors.New("invalid reply: calendar is missing")
+	}
+	events, err := parseJCal(config.SourceInfo{}, bytes.NewReader(response.Calendar))
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// hookProcess is a running hook, it handles one batch at a time
+type hookProcess struct {
+	lock   sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *tailWriter
+	// pending receives the next line the hook writes, it is nil while no call waits for a reply
+	pendingLock sync.Mutex
+	pending     chan []byte
+	// exited is closed once the hook has exited, waitErr is set before
+	exited  chan struct{}
+	waitErr error
+	stop    sync.Once
+}
+
+var (
+	hooksLock sync.Mutex
+	hooks     = map[string]*hookProcess{}
+)
+
+// hookKey identifies the process of a hook, modifiers with the same command share it
+func hookKey(def *config.Exec) string {
+	parts := append([]string{def.Command, def.Dir}, def.Args...)
+	env := execEnv(def.Env)
+	sort.Strings(env)
+	return strings.Join(append(parts, env...), "\x00")
+}
+
+// startHook returns the running process of the hook, starting it when it is not running
+func startHook(def *config.Exec) (*hookProcess, error) {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+
+	key := hookKey(def)
+	if p, ok := hooks[key]; ok {
+		return p, nil
+	}
+
+	cmd := exec.Command(def.Command, def.Args...)
+	cmd.Dir = def.Dir
+	cmd.Env = execEnv(def.Env)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	p := &hookProcess{cmd: cmd, stdin: stdin, stderr: &tailWriter{}, exited: make(chan struct{})}
+	cmd.Stderr = p.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("hook %s could not be started: %w", def.Command, err)
+	}
+	log.Logger.Info("Started hook", "command", def.Command, "pid", cmd.Process.Pid)
+
+	go p.read(stdout)
+	hooks[key] = p
+	return p, nil
+}
+
+// stopHook kills the process of the hook, unless it has already been replaced
+func stopHook(def *config.Exec, p *hookProcess) {
+	hooksLock.Lock()
+	key := hookKey(def)
+	if hooks[key] == p {
+		delete(hooks, key)
+	}
+	hooksLock.Unlock()
+
+	p.kill()
+}
+
+// StopHooks kills the processes of all hooks, they would otherwise outlive the merger
+func StopHooks() {
+	hooksLock.Lock()
+	running := hooks
+	hooks = map[string]*hookProcess{}
+	hooksLock.Unlock()
+
+	for _, p := range running {
+		log.Logger.Info("Stopping hook", "command", p.cmd.Path, "pid", p.cmd.Process.Pid)
+		p.kill()
+	}
+}
+
+func (p *hookProcess) kill() {
+	p.stop.Do(func() {
+		_ = p.stdin.Close()
+		_ = p.cmd.Process.Kill()
+	})
+}
+
+// read passes every line the hook writes to the waiting call, and reaps the process once it exits
+func (p *hookProcess) read(stdout io.Reader) {
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			p.deliver(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+	p.waitErr = p.cmd.Wait()
+	close(p.exited)
+}
+
+// deliver passes the line to the waiting call. Lines written while no call waits, or after
+// the reply, are dropped so they are not taken for the reply to the next batch.
+func (p *hookProcess) deliver(line []byte) {
+	p.pendingLock.Lock()
+	pending := p.pending
+	p.pending = nil
+	p.pendingLock.Unlock()
+
+	if pending == nil {
+		log.Logger.Warn("Dropping unexpected hook output", "command", p.cmd.Path, "output", tail(strings.TrimSpace(string(line)), stderrExcerpt))
+		return
+	}
+	pending <- line
+}
+
+// call writes the request and waits for the reply
+func (p *hookProcess) call(request []byte, timeout time.Duration) ([]byte, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	reply := make(chan []byte, 1)
+	p.pendingLock.Lock()
+	p.pending = reply
+	p.pendingLock.Unlock()
+	defer func() {
+		p.pendingLock.Lock()
+		p.pending = nil
+		p.pendingLock.Unlock()
+	}()
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := p.stdin.Write(request)
+		written <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case err := <-written:
+			if err != nil {
+				return nil, fmt.Errorf("writing to hook: %w", err)
+			}
+			written = nil
+		case line := <-reply:
+			return line, nil
+		case <-p.exited:
+			// a reply written just before exiting still counts
+			select {
+			case line := <-reply:
+				return line, nil
+			default:
+			}
+			return nil, p.exitError()
+		case <-timer.C:
+			return nil, fmt.Errorf("hook did not reply within %s%s", timeout, stderrSuffix(p.stderr.String()))
+		}
+	}
+}
+
+// exitError describes why the hook stopped, only valid once exited is closed
+func (p *hookProcess) exitError() error {
+	var exitErr *exec.ExitError
+	if errors.As(p.waitErr, &exitErr) {
+		return fmt.Errorf("hook exited with code %d%s", exitErr.ExitCode(), stderrSuffix(p.stderr.String()))
+	}
+	return fmt.Errorf("hook exited%s", stderrSuffix(p.stderr.String()))
+}
+
+// tailWriter keeps the end of what is written to it, hooks may log to stderr for as long as they run
+type tailWriter struct {
+	lock sync.Mutex
+	buf  []byte
+}
+
+func (w *tailWriter) Write(b []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.buf = append(w.buf, b...)
+	if len(w.buf) > 2*stderrExcerpt {
+		w.buf = append([]byte(nil), w.buf[len(w.buf)-stderrExcerpt:]...)
+	}
+	return len(b), nil
+}
+
+func (w *tailWriter) String() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return tail(strings.TrimSpace(string(w.buf)), stderrExcerpt)
+}
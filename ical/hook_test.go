@@ -0,0 +1,228 @@
+package ical
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHookProcess is not a test, it is run as the hook by the other tests. It uppercases the
+// summaries, drops events with the summary "drop" and records the number of the batch.
+func TestHookProcess(t *testing.T) {
+	mode := os.Getenv("ICAL_MERGER_HOOK")
+	if mode == "" {
+		return
+	}
+
+	if mode == "chatty" {
+		// output no call waits for, such as a banner, must not be taken for a reply
+		fmt.Println(`{"error": "not a reply"}`)
+		fmt.Fprintln(os.Stderr, "ready")
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for batch := 1; scanner.Scan(); batch++ {
+		switch mode {
+		case "exit":
+			fmt.Fprintln(os.Stderr, "cannot reach the ticket system")
+			os.Exit(2)
+		case "hang":
+			time.Sleep(time.Minute)
+		case "error":
+			fmt.Println(`{"error": "no tickets today"}`)
+			continue
+		}
+
+		var request struct {
+			Calendar []any `json:"calendar"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			fmt.Printf("{\"error\": %q}\n", err.Error())
+			continue
+		}
+
+		kept := []any{}
+		for _, c := range request.Calendar[2].([]any) {
+			event := c.([]any)
+			properties := event[1].([]any)
+			drop := false
+			for _, p := range properties {
+				property := p.([]any)
+				if property[0] == "summary" {
+					drop = property[3] == "drop"
+					property[3] = strings.ToUpper(property[3].(string))
+				}
+			}
+			event[1] = append(properties, []any{"x-batch", map[string]any{}, "integer", batch})
+			if !drop {
+				kept = append(kept, event)
+			}
+		}
+		request.Calendar[2] = kept
+
+		reply, _ := json.Marshal(map[string]any{"calendar": request.Calendar})
+		fmt.Println(string(reply))
+	}
+	os.Exit(0)
+}
+
+func hookModifier(mode string) config.Modifier {
+	return config.Modifier{Name: "upper", Action: config.HOOK, Hook: &config.Exec{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestHookProcess$"},
+		Env:     map[string]string{"ICAL_MERGER_HOOK": mode},
+		Timeout: "PT2S",
+	}}
+}
+
+func TestHook(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
+	newCal := func() *LoadediCal {
+		first := newEventWithSpan("first", start, time.Hour)
+		first.SetSummary("standup, daily")
+		first.AddRrule("FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4")
+		second := newEventWithSpan("second", start.Add(2*time.Hour), time.Hour)
+		second.SetSummary("drop")
+		other := newEventWithSpan("other", start.Add(4*time.Hour), time.Hour)
+		other.SetSummary("untouched")
+
+		cal := newLoadedCal("work", first, second, other)
+		modifier := hookModifier("upper")
+		modifier.Filters = []config.Rule{{Check: FilterNotEqualsTerm, Component: "UID", Data: []string{"other"}}}
+		cal.source.Modifiers = []config.Modifier{modifier}
+		return cal
+	}
+
+	cal := newCal()
+	cal.Filter()
+	events := cal.Events()
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "first", events[0].Id())
+		assert.Equal(t, `STANDUP\, DAILY`, events[0].GetProperty(ics.ComponentPropertySummary).Value)
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4", events[0].GetProperty(ics.ComponentPropertyRrule).Value)
+		s, e, err := eventSpan(events[0])
+		assert.NoError(t, err)
+		assert.True(t, start.Equal(s))
+		assert.True(t, start.Add(time.Hour).Equal(e))
+		assert.Equal(t, "other", events[1].Id())
+	}
+
+	// the hook keeps running between batches
+	batch := func(cal *LoadediCal) int {
+		n, _ := strconv.Atoi(cal.Events()[0].GetProperty("X-BATCH").Value)
+		return n
+	}
+	first := batch(cal)
+	assert.Positive(t, first)
+	cal = newCal()
+	cal.Filter()
+	assert.Equal(t, first+1, batch(cal))
+}
+
+func TestHookDropsUnexpectedOutput(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	defer StopHooks()
+
+	modifier := hookModifier("chatty")
+	p, err := startHook(modifier.Hook)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return p.stderr.String() == "ready" }, 2*time.Second, 10*time.Millisecond)
+	// give read the time to see the banner, it was written before ready
+	time.Sleep(100 * time.Millisecond)
+
+	e := newEventWithSpan("first", time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC), time.Hour)
+	e.SetSummary("standup")
+	events, err := callHook(&modifier, []*ics.VEvent{e})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "STANDUP", events[0].GetProperty(ics.ComponentPropertySummary).Value)
+	}
+}
+
+func TestStopHooks(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+
+	modifier := hookModifier("upper")
+	p, err := startHook(modifier.Hook)
+	assert.NoError(t, err)
+
+	StopHooks()
+	select {
+	case <-p.exited:
+	case <-time.After(2 * time.Second):
+		t.Fatal("hook still running")
+	}
+
+	// a stopped hook is started again for the next batch
+	again, err := startHook(modifier.Hook)
+	assert.NoError(t, err)
+	assert.NotSame(t, p, again)
+	StopHooks()
+}
+
+func TestHookFailuresKeepEvents(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
+	for _, mode := range []string{"exit", "hang", "error"} {
+		e := newEventWithSpan("first", start, time.Hour)
+		e.SetSummary("standup")
+		modifier := hookModifier(mode)
+		modifier.Hook.Timeout = "PT1S"
+
+		events := runHook([]*ics.VEvent{e}, []*ics.VEvent{e}, &modifier)
+		if assert.Len(t, events, 1, mode) {
+			assert.Equal(t, "standup", events[0].GetProperty(ics.ComponentPropertySummary).Value, mode)
+		}
+	}
+
+	modifier := hookModifier("exit")
+	e := newEventWithSpan("first", start, time.Hour)
+	_, err := callHook(&modifier, []*ics.VEvent{e})
+	assert.EqualError(t, err, "hook exited with code 2: cannot reach the ticket system")
+}
+
+func TestJCalRoundTrip(t *testing.T) {
+	e := newEventWithSpan("trip", time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC), time.Hour)
+	e.SetSummary("Trip; to Paris, France")
+	e.SetProperty(ics.ComponentPropertyGeo, "48.85;2.35")
+	e.SetProperty(ics.ComponentPropertyCategories, "Travel,Work")
+	e.SetProperty(ics.ComponentPropertySequence, "3")
+	e.AddExdate("20240311T090000Z")
+	e.SetAllDayEndAt(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC))
+	e.AddRrule("FREQ=WEEKLY;UNTIL=20240401T000000Z;BYMONTHDAY=-1")
+	alarm := e.AddAlarm()
+	alarm.SetAction(ics.ActionDisplay)
+	alarm.SetTrigger("-PT15M")
+
+	doc, err := json.Marshal(jcalCalendar([]*ics.VEvent{e}))
+	assert.NoError(t, err)
+	events, err := parseJCal(config.SourceInfo{}, strings.NewReader(string(doc)))
+	assert.NoError(t, err)
+	if !assert.Len(t, events, 1) {
+		return
+	}
+
+	got := events[0]
+	for _, property := range []ics.ComponentProperty{ics.ComponentPropertySummary, ics.ComponentPropertyDtStart,
+		ics.ComponentPropertyDtEnd, ics.ComponentPropertyGeo, ics.ComponentPropertyCategories,
+		ics.ComponentPropertySequence, ics.ComponentPropertyExdate} {
+		assert.Equal(t, e.GetProperty(property).Value, got.GetProperty(property).Value, property)
+	}
+	assert.Equal(t, []string{"DATE"}, got.GetProperty(ics.ComponentPropertyDtEnd).ICalParameters["VALUE"])
+	assert.Equal(t, "FREQ=WEEKLY;BYMONTHDAY=-1;UNTIL=20240401T000000Z", got.GetProperty(ics.ComponentPropertyRrule).Value)
+	assert.Len(t, got.Alarms(), 1)
+}
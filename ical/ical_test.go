@@ -1,10 +1,12 @@
 package ical
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
 	ics "github.com/arran4/golang-ical"
 	"github.com/stretchr/testify/assert"
 )
@@ -101,3 +103,186 @@ func TestModifierFirstOfYear(t *testing.T) {
 	// TODO fix this test or underlying logic
 	// assert.False(t, ical.modifierFirstOfYear(newEventWithDate(time.Now().AddDate(-5, 0, 0))))
 }
+
+func TestRegisterCheck(t *testing.T) {
+	RegisterCheck("TEST_HAS_SUMMARY", func(e *ics.VEvent, ctx RuleContext) bool {
+		return e.GetProperty(ics.ComponentPropertySummary) != nil
+	})
+	assert.True(t, config.HasCheck("TEST_HAS_SUMMARY"))
+	assert.True(t, newCalWithRule("TEST_HAS_SUMMARY", "", nil).Check(newEventWithProperty(ics.ComponentPropertySummary, "Team Meeting")))
+	assert.False(t, newCalWithRule("TEST_HAS_SUMMARY", "", nil).Check(newEventWithProperty(ics.ComponentPropertyLocation, "Office")))
+	assert.Panics(t, func() { RegisterCheck("TEST_HAS_SUMMARY", func(*ics.VEvent, RuleContext) bool { return true }) })
+}
+
+func TestRegisterAction(t *testing.T) {
+	RegisterAction("TEST_UPPER", func(e *ics.VEvent, ctx ModifierContext) error {
+		e.SetProperty(ics.ComponentProperty(ctx.Modifier.Component), strings.ToUpper(e.GetProperty(ics.ComponentProperty(ctx.Modifier.Component)).Value))
+		return nil
+	})
+	assert.True(t, config.HasAction("TEST_UPPER"))
+
+	cal := &LoadediCal{source: config.SourceInfo{Modifiers: []config.Modifier{{Component: "SUMMARY", Action: "TEST_UPPER"}}}}
+	e := cal.Modify(newEventWithProperty(ics.ComponentPropertySummary, "Team Meeting"))
+	assert.Equal(t, "TEAM MEETING", e.GetProperty(ics.ComponentPropertySummary).Value)
+}
+
+func TestFilterFuzzy(t *testing.T) {
+	ical := &LoadediCal{}
+	rule := config.Rule{Component: "SUMMARY", Data: []string{"Lecture"}}
+	assert.True(t, ical.filterFuzzy(&rule, newEventWithProperty(ics.ComponentPropertySummary, "CS101 Lectrue")))
+	assert.True(t, ical.filterFuzzy(&rule, newEventWithProperty(ics.ComponentPropertySummary, "CS101 lecture")))
+	assert.False(t, ical.filterFuzzy(&rule, newEventWithProperty(ics.ComponentPropertySummary, "CS101 Lab")))
+
+	distance := 2
+	rule.Distance = &distance
+	assert.True(t, ical.filterFuzzy(&rule, newEventWithProperty(ics.ComponentPropertySummary, "Lectrue")))
+	assert.True(t, ical.filterFuzzy(&rule, newEventWithProperty(ics.ComponentPropertySummary, "Lctre")))
+
+	// a distance of 0 only allows exact matches
+	distance = 0
+	assert.False(t, ical.filterFuzzy(&rule, newEventWithProperty(ics.ComponentPropertySummary, "CS101 Lectrue")))
+	assert.True(t, ical.filterFuzzy(&rule, newEventWithProperty(ics.ComponentPropertySummary, "CS101 lecture")))
+}
+
+func TestSubstringDistance(t *testing.T) {
+	assert.Equal(t, 0, substringDistance([]rune("meet"), []rune("team meeting")))
+	assert.Equal(t, 1, substringDistance([]rune("meeting"), []rune("team meting")))
+	assert.Equal(t, 1, substringDistance([]rune("meeting"), []rune("team meteing")))
+	assert.Equal(t, 3, substringDistance([]rune("abc"), []rune("")))
+	assert.Equal(t, 0, substringDistance([]rune(""), []rune("abc")))
+}
+
+func TestUnicodeMatching(t *testing.T) {
+	ical := &LoadediCal{}
+
+	// case folding
+	rule := config.Rule{Component: "SUMMARY", Data: []string{"STRASSE"}}
+	assert.True(t, ical.filterEquals(&rule, newEventWithProperty(ics.ComponentPropertySummary, "straße")))
+
+	// accent folding
+	rule = config.Rule{Component: "SUMMARY", Data: []string{"Okonomie"}, StripAccents: true}
+	assert.True(t, ical.filterEquals(&rule, newEventWithProperty(ics.ComponentPropertySummary, "Ökonomie")))
+	rule.StripAccents = false
+	assert.False(t, ical.filterEquals(&rule, newEventWithProperty(ics.ComponentPropertySummary, "Ökonomie")))
+
+	// normalization, precomposed against decomposed
+	rule = config.Rule{Component: "SUMMARY", Data: []string{"Ökonomie"}, Normalize: config.NFC}
+	assert.True(t, ical.filterEquals(&rule, newEventWithProperty(ics.ComponentPropertySummary, "Ökonomie")))
+	rule.Normalize = ""
+	assert.False(t, ical.filterEquals(&rule, newEventWithProperty(ics.ComponentPropertySummary, "Ökonomie")))
+
+	// whitespace collapsing
+	rule = config.Rule{Component: "SUMMARY", Data: []string{"Team Meeting"}, CollapseWhitespace: true}
+	assert.True(t, ical.filterEquals(&rule, newEventWithProperty(ics.ComponentPropertySummary, "  Team \t Meeting ")))
+}
+
+func newModifier(name string, action config.Action, data string, filters ...config.Rule) config.Modifier {
+	return config.Modifier{Name: name, Component: "SUMMARY", Action: action, Data: data, Filters: filters}
+}
+
+func TestModifyJudgesModifiersIndependently(t *testing.T) {
+	contains := func(s string) config.Rule {
+		return config.Rule{Check: FilterContainsTerm, Component: "SUMMARY", Data: []string{s}}
+	}
+
+	cal := &LoadediCal{source: config.SourceInfo{Modifiers: []config.Modifier{
+		newModifier("exam", config.PREPEND, "[EXAM] ", contains("exam")),
+		newModifier("lecture", config.PREPEND, "[LECTURE] ", contains("lecture")),
+		newModifier("all", config.APPEND, "!"),
+		newModifier("both", config.APPEND, "?", contains("exam"), contains("lecture")),
+	}}}
+
+	assert.Equal(t, "[LECTURE] Lecture!", cal.Modify(newEventWithProperty(ics.ComponentPropertySummary, "Lecture")).GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Equal(t, "[EXAM] Exam!", cal.Modify(newEventWithProperty(ics.ComponentPropertySummary, "Exam")).GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Equal(t, "[LECTURE] [EXAM] Exam lecture!?", cal.Modify(newEventWithProperty(ics.ComponentPropertySummary, "Exam lecture")).GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Equal(t, "Lab!", cal.Modify(newEventWithProperty(ics.ComponentPropertySummary, "Lab")).GetProperty(ics.ComponentPropertySummary).Value)
+}
+
+func TestModifyFlowAndElse(t *testing.T) {
+	contains := func(s string) config.Rule {
+		return config.Rule{Check: FilterContainsTerm, Component: "SUMMARY", Data: []string{s}}
+	}
+
+	exam := newModifier("exam", config.PREPEND, "[EXAM] ", contains("exam"))
+	exam.Flow = config.FlowStop
+	exam.Else = []config.Modifier{
+		newModifier("lecture", config.PREPEND, "[LECTURE] ", contains("lecture")),
+		newModifier("other", config.PREPEND, "[OTHER] ", config.Rule{Check: FilterNotContainsTerm, Component: "SUMMARY", Data: []string{"lecture"}}),
+	}
+
+	cal := &LoadediCal{source: config.SourceInfo{Modifiers: []config.Modifier{
+		exam,
+		newModifier("all", config.APPEND, "!"),
+	}}}
+
+	assert.Equal(t, "[EXAM] Exam lecture", cal.Modify(newEventWithProperty(ics.ComponentPropertySummary, "Exam lecture")).GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Equal(t, "[LECTURE] Lecture!", cal.Modify(newEventWithProperty(ics.ComponentPropertySummary, "Lecture")).GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Equal(t, "[OTHER] Lab!", cal.Modify(newEventWithProperty(ics.ComponentPropertySummary, "Lab")).GetProperty(ics.ComponentPropertySummary).Value)
+
+	// a stop inside an else branch ends the pipeline as well
+	exam.Else[0].Flow = config.FlowStop
+	assert.Equal(t, "[LECTURE] Lecture", cal.Modify(newEventWithProperty(ics.ComponentPropertySummary, "Lecture")).GetProperty(ics.ComponentPropertySummary).Value)
+}
+
+func TestFirstOfDayWithMultipleModifiers(t *testing.T) {
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)
+	first := newEventWithSpan("1", day.Add(9*time.Hour), time.Hour)
+	first.SetSummary("Lecture")
+	second := newEventWithSpan("2", day.Add(8*time.Hour), time.Hour)
+	second.SetSummary("Lecture")
+	next := newEventWithSpan("3", day.AddDate(0, 0, 1).Add(9*time.Hour), time.Hour)
+	next.SetSummary("Lecture")
+
+	firstOfDay := config.Rule{Check: ModifierFirstOfDayTerm}
+	cal := newLoadedCal("school", first, second, next)
+	cal.source.Modifiers = []config.Modifier{
+		newModifier("early", config.PREPEND, "[FIRST] ", firstOfDay),
+		newModifier("alarm", config.ALARM, "-PT30M", firstOfDay),
+	}
+
+	events := cal.FilteredEvents()
+	assert.Equal(t, "Lecture", events[0].GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Empty(t, events[0].Alarms())
+	assert.Equal(t, "[FIRST] Lecture", events[1].GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Len(t, events[1].Alarms(), 1)
+	assert.Equal(t, "[FIRST] Lecture", events[2].GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Len(t, events[2].Alarms(), 1)
+}
+
+func TestEndpointRulesAndModifiers(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)
+
+	work := newLoadedCal("work",
+		newEventWithSpan("w1", day.Add(9*time.Hour), time.Hour),
+		newEventWithSpan("w2", day.Add(24*time.Hour+8*time.Hour), time.Hour),
+	)
+	personal := newLoadedCal("personal",
+		newEventWithSpan("p1", day.Add(7*time.Hour), time.Hour),
+		newEventWithSpan("p2", day.Add(24*time.Hour+12*time.Hour), time.Hour),
+		newEventWithSpan("cancelled", day.Add(6*time.Hour), time.Hour),
+	)
+	personal.events[2].SetStatus(ics.ObjectStatusCancelled)
+
+	c := &CustomCalender{
+		source: config.Source{
+			Name:  "merged",
+			Rules: []config.Rule{{Component: "STATUS", Check: FilterNotEqualsTerm, Data: []string{"CANCELLED"}}},
+			Modifiers: []config.Modifier{{
+				Name:    "First event",
+				Action:  config.ALARM,
+				Data:    "-PT30M",
+				Filters: []config.Rule{{Check: ModifierFirstOfDayTerm}},
+			}},
+		},
+		loaded: []*LoadediCal{work, personal},
+	}
+	work.parent, personal.parent = c, c
+
+	alarms := map[string]int{}
+	events := c.mergeLoadediCals().Events()
+	for _, e := range events {
+		alarms[e.Id()] = len(e.Alarms())
+	}
+	assert.Equal(t, map[string]int{"w1": 0, "w2": 1, "p1": 1, "p2": 0}, alarms)
+}
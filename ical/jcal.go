@@ -0,0 +1,507 @@
+package ical
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+)
+
+// jCal and xCal are converted to iCalendar text, so their events are parsed like any other calendar.
+// Events are converted to jCal for hooks.
+
+// icsParam is a parameter of an iCalendar property
+type icsParam struct {
+	name   string
+	values []string
+}
+
+// icsBuilder writes iCalendar text
+type icsBuilder struct {
+	strings.Builder
+}
+
+func (b *icsBuilder) line(name, value string) {
+	b.WriteString(strings.ToUpper(name) + ":" + value + "\r\n")
+}
+
+// property writes a property, valueType is the jCal or xCal type of the values
+func (b *icsBuilder) property(name string, params []icsParam, valueType string, values []string) {
+	b.WriteString(strings.ToUpper(name))
+	hasValue := false
+	for _, p := range params {
+		hasValue = hasValue || strings.EqualFold(p.name, "value")
+		quoted := make([]string, len(p.values))
+		for i, v := range p.values {
+			if strings.ContainsAny(v, ":;,") {
+				v = `"` + v + `"`
+			}
+			quoted[i] = v
+		}
+		b.WriteString(";" + strings.ToUpper(p.name) + "=" + strings.Join(quoted, ","))
+	}
+	// other types are the default of the properties using them
+	if !hasValue && (valueType == "date" || valueType == "period") {
+		b.WriteString(";VALUE=" + strings.ToUpper(valueType))
+	}
+	b.WriteString(":" + strings.Join(values, ",") + "\r\n")
+}
+
+// icsValue converts a value of the jCal or xCal type to its iCalendar form
+func icsValue(valueType, value string) string {
+	switch valueType {
+	case "text":
+		return ics.ToText(value)
+	case "date", "date-time", "time":
+		return strings.NewReplacer("-", "", ":", "").Replace(value)
+	case "utc-offset":
+		return strings.ReplaceAll(value, ":", "")
+	case "period":
+		start, end, _ := strings.Cut(value, "/")
+		if !strings.HasPrefix(end, "P") {
+			end = icsValue("date-time", end)
+		}
+		return icsValue("date-time", start) + "/" + end
+	}
+	return value
+}
+
+// recurValue converts the parts of a recurrence rule, FREQ comes first and the others keep their order
+func recurValue(names []string, values map[string][]string) string {
+	parts := []string{}
+	for _, name := range names {
+		vals := values[name]
+		if name == "until" {
+			for i, v := range vals {
+				vals[i] = icsValue("date-time", v)
+			}
+		}
+		part := strings.ToUpper(name) + "=" + strings.Join(vals, ",")
+		if name == "freq" {
+			parts = append([]string{part}, parts...)
+		} else {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// parseJCal returns the events of a jCal (RFC 7265) calendar
+func parseJCal(source config.SourceInfo, r io.Reader) ([]*ics.VEvent, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var b icsBuilder
+	if err := b.jcalComponent(doc); err != nil {
+		return nil, fmt.Errorf("invalid jCal: %w", err)
+	}
+	return parseICS(source, strings.NewReader(b.String()))
+}
+
+func (b *icsBuilder) jcalComponent(c any) error {
+	parts, ok := c.([]any)
+	if !ok || len(parts) != 3 {
+		return errors.New("a component must be an array of its name, properties and components")
+	}
+	name, ok := parts[0].(string)
+	properties, okProperties := parts[1].([]any)
+	components, okComponents := parts[2].([]any)
+	if !ok || !okProperties || !okComponents {
+		return errors.New("a component must be an array of its name, properties and components")
+	}
+
+	b.line("BEGIN", strings.ToUpper(name))
+	for _, p := range properties {
+		if err := b.jcalProperty(p); err != nil {
+			return err
+		}
+	}
+	for _, sub := range components {
+		if err := b.jcalComponent(sub); err != nil {
+			return err
+		}
+	}
+	b.line("END", strings.ToUpper(name))
+	return nil
+}
+
+func (b *icsBuilder) jcalProperty(p any) error {
+	parts, ok := p.([]any)
+	if !ok || len(parts) < 4 {
+		return errors.New("a property must be an array of its name, parameters, type and values")
+	}
+	name, ok := parts[0].(string)
+	rawParams, okParams := parts[1].(map[string]any)
+	valueType, okType := parts[2].(string)
+	if !ok || !okParams || !okType {
+		return errors.New("a property must be an array of its name, parameters, type and values")
+	}
+
+	names := make([]string, 0, len(rawParams))
+	for param := range rawParams {
+		names = append(names, param)
+	}
+	sort.Strings(names)
+	params := make([]icsParam, 0, len(names))
+	for _, param := range names {
+		params = append(params, icsParam{name: param, values: jcalStrings(rawParams[param])})
+	}
+
+	values := make([]string, 0, len(parts)-3)
+	for _, v := range parts[3:] {
+		switch v := v.(type) {
+		case string:
+			values = append(values, icsValue(valueType, v))
+		case map[string]any:
+			values = append(values, jcalRecur(v))
+		case []any:
+			// structured values such as GEO and REQUEST-STATUS
+			values = append(values, strings.Join(jcalStrings(v), ";"))
+		default:
+			values = append(values, strings.ToUpper(jsonString(v)))
+		}
+	}
+	b.property(name, params, valueType, values)
+	return nil
+}
+
+func jcalRecur(rule map[string]any) string {
+	names := make([]string, 0, len(rule))
+	values := map[string][]string{}
+	for name, v := range rule {
+		names = append(names, name)
+		values[name] = jcalStrings(v)
+	}
+	sort.Strings(names)
+	return recurValue(names, values)
+}
+
+// jcalStrings returns a value or an array of values as text
+func jcalStrings(v any) []string {
+	list, ok := v.([]any)
+	if !ok {
+		return []string{jsonString(v)}
+	}
+	values := make([]string, len(list))
+	for i, item := range list {
+		values[i] = jsonString(item)
+	}
+	return values
+}
+
+// xmlNode is any element of an xCal document
+type xmlNode struct {
+	XMLName xml.Name
+	Nodes   []xmlNode `xml:",any"`
+	Text    string    `xml:",chardata"`
+}
+
+// parseXCal returns the events of an xCal (RFC 6321) calendar
+func parseXCal(source config.SourceInfo, r io.Reader) ([]*ics.VEvent, error) {
+	var root xmlNode
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, err
+	}
+	if root.XMLName.Local != "icalendar" {
+		return nil, fmt.Errorf("invalid xCal: root element is %s, not icalendar", root.XMLName.Local)
+	}
+
+	var events []*ics.VEvent
+	for _, cal := range root.Nodes {
+		var b icsBuilder
+		b.xcalComponent(cal)
+		parsed, err := parseICS(source, strings.NewReader(b.String()))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, parsed...)
+	}
+	return events, nil
+}
+
+func (b *icsBuilder) xcalComponent(n xmlNode) {
+	b.line("BEGIN", strings.ToUpper(n.XMLName.Local))
+	for _, child := range n.Nodes {
+		switch child.XMLName.Local {
+		case "properties":
+			for _, p := range child.Nodes {
+				b.xcalProperty(p)
+			}
+		case "components":
+			for _, sub := range child.Nodes {
+				b.xcalComponent(sub)
+			}
+		}
+	}
+	b.line("END", strings.ToUpper(n.XMLName.Local))
+}
+
+func (b *icsBuilder) xcalProperty(n xmlNode) {
+	var (
+		params    []icsParam
+		valueType string
+		values    []string
+	)
+	for _, child := range n.Nodes {
+		if child.XMLName.Local == "parameters" {
+			for _, p := range child.Nodes {
+				param := icsParam{name: p.XMLName.Local}
+				for _, v := range p.Nodes {
+					param.values = append(param.values, strings.TrimSpace(v.Text))
+				}
+				params = append(params, param)
+			}
+			continue
+		}
+		valueType = child.XMLName.Local
+		values = append(values, xcalValue(child))
+	}
+	b.property(n.XMLName.Local, params, valueType, values)
+}
+
+func xcalValue(n xmlNode) string {
+	switch n.XMLName.Local {
+	case "recur":
+		var names []string
+		values := map[string][]string{}
+		for _, part := range n.Nodes {
+			name := part.XMLName.Local
+			if _, ok := values[name]; !ok {
+				names = append(names, name)
+			}
+			values[name] = append(values[name], strings.TrimSpace(part.Text))
+		}
+		return recurValue(names, values)
+	case "geo", "request-status":
+		parts := make([]string, len(n.Nodes))
+		for i, part := range n.Nodes {
+			parts[i] = icsValue(part.XMLName.Local, strings.TrimSpace(part.Text))
+		}
+		return strings.Join(parts, ";")
+	case "text":
+		return icsValue("text", n.Text)
+	}
+	return icsValue(n.XMLName.Local, strings.TrimSpace(n.Text))
+}
+
+// jcalTypes are the default value types of the properties, other properties are text
+var jcalTypes = map[string]string{
+	"dtstart":          "date-time",
+	"dtend":            "date-time",
+	"dtstamp":          "date-time",
+	"due":              "date-time",
+	"completed":        "date-time",
+	"created":          "date-time",
+	"last-modified":    "date-time",
+	"recurrence-id":    "date-time",
+	"exdate":           "date-time",
+	"rdate":            "date-time",
+	"duration":         "duration",
+	"trigger":          "duration",
+	"rrule":            "recur",
+	"exrule":           "recur",
+	"geo":              "float",
+	"sequence":         "integer",
+	"priority":         "integer",
+	"percent-complete": "integer",
+	"repeat":           "integer",
+	"organizer":        "cal-address",
+	"attendee":         "cal-address",
+	"url":              "uri",
+	"attach":           "uri",
+	"tzurl":            "uri",
+	"tzoffsetfrom":     "utc-offset",
+	"tzoffsetto":       "utc-offset",
+}
+
+// jcalCalendar returns the events as a jCal calendar
+func jcalCalendar(events []*ics.VEvent) []any {
+	components := make([]any, 0, len(events))
+	for _, e := range events {
+		components = append(components, jcalFromComponent("vevent", e))
+	}
+	return []any{"vcalendar", []any{
+		[]any{"version", map[string]any{}, "text", "2.0"},
+		[]any{"prodid", map[string]any{}, "text", "-//ical-merger//EN"},
+	}, components}
+}
+
+func jcalFromComponent(name string, c ics.Component) []any {
+	properties := []any{}
+	for _, p := range c.UnknownPropertiesIANAProperties() {
+		properties = append(properties, jcalFromProperty(p))
+	}
+
+	components := []any{}
+	for _, sub := range c.SubComponents() {
+		switch sub := sub.(type) {
+		case *ics.VAlarm:
+			components = append(components, jcalFromComponent("valarm", sub))
+		case *ics.GeneralComponent:
+			components = append(components, jcalFromComponent(strings.ToLower(sub.Token), sub))
+		}
+	}
+	return []any{name, properties, components}
+}
+
+func jcalFromProperty(p ics.IANAProperty) []any {
+	name := strings.ToLower(p.IANAToken)
+
+	valueType, ok := jcalTypes[name]
+	if !ok {
+		valueType = "text"
+		if strings.HasPrefix(name, "x-") {
+			valueType = "unknown"
+		}
+	}
+	params := map[string]any{}
+	for key, values := range p.ICalParameters {
+		key = strings.ToLower(key)
+		switch {
+		case key == "value" && len(values) > 0:
+			valueType = strings.ToLower(values[0])
+		case len(values) == 1:
+			params[key] = values[0]
+		default:
+			list := make([]any, len(values))
+			for i, v := range values {
+				list[i] = v
+			}
+			params[key] = list
+		}
+	}
+	// dates without VALUE=DATE are common
+	if valueType == "date-time" && !strings.Contains(p.Value, "T") {
+		valueType = "date"
+	}
+
+	return append([]any{name, params, valueType}, jcalValues(valueType, p.Value)...)
+}
+
+// jcalValues converts an iCalendar value of the type to its jCal values
+func jcalValues(valueType, value string) []any {
+	switch valueType {
+	case "text":
+		var values []any
+		for _, v := range splitText(value) {
+			values = append(values, ics.FromText(v))
+		}
+		return values
+	case "date", "date-time", "period":
+		var values []any
+		for _, v := range strings.Split(value, ",") {
+			start, end, isPeriod := strings.Cut(v, "/")
+			v = jcalTime(start)
+			if isPeriod {
+				if !strings.HasPrefix(end, "P") {
+					end = jcalTime(end)
+				}
+				v += "/" + end
+			}
+			values = append(values, v)
+		}
+		return values
+	case "utc-offset":
+		if len(value) >= 5 {
+			value = value[:3] + ":" + value[3:5] + strings.TrimSuffix(":"+value[5:], ":")
+		}
+		return []any{value}
+	case "integer":
+		if i, err := strconv.Atoi(value); err == nil {
+			return []any{i}
+		}
+	case "float":
+		var parts []any
+		for _, part := range strings.Split(value, ";") {
+			f, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return []any{value}
+			}
+			parts = append(parts, f)
+		}
+		if len(parts) == 1 {
+			return parts
+		}
+		return []any{parts}
+	case "recur":
+		return []any{jcalRecurFrom(value)}
+	}
+	return []any{value}
+}
+
+// jcalTime converts an iCalendar date or date-time such as 20240304T090000Z to 2024-03-04T09:00:00Z
+func jcalTime(s string) string {
+	date, clock, hasClock := strings.Cut(s, "T")
+	if len(date) == 8 {
+		date = date[:4] + "-" + date[4:6] + "-" + date[6:]
+	}
+	if !hasClock {
+		return date
+	}
+	if len(clock) >= 6 {
+		clock = clock[:2] + ":" + clock[2:4] + ":" + clock[4:]
+	}
+	return date + "T" + clock
+}
+
+// recurNumbers are the recurrence rule parts holding integers
+var recurNumbers = []string{"count", "interval", "bysecond", "byminute", "byhour", "bymonthday", "byyearday",
+	"byweekno", "bymonth", "bysetpos"}
+
+func jcalRecurFrom(rule string) map[string]any {
+	recur := map[string]any{}
+	for _, part := range strings.Split(strings.TrimPrefix(rule, "RRULE:"), ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(name)
+		if name == "until" {
+			recur[name] = jcalTime(value)
+			continue
+		}
+
+		var values []any
+		for _, v := range strings.Split(value, ",") {
+			if i, err := strconv.Atoi(v); err == nil && slices.Contains(recurNumbers, name) {
+				values = append(values, i)
+			} else {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 1 {
+			recur[name] = values[0]
+		} else {
+			recur[name] = values
+		}
+	}
+	return recur
+}
+
+// splitText splits a text value on the commas separating its values, escaped commas are kept
+func splitText(s string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
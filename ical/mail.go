@@ -0,0 +1,310 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+)
+
+func init() {
+	RegisterSource(config.SourceMail, loadMail, config.RequireMail)
+}
+
+// invite is the latest known version of an event sent by mail
+type invite struct {
+	event     *ics.VEvent
+	sequence  int
+	stamp     time.Time
+	cancelled bool
+}
+
+// newer reports whether the invite replaces other, a higher SEQUENCE wins and a later DTSTAMP
+// breaks ties. Otherwise the invite read last wins.
+func (i invite) newer(other invite) bool {
+	if i.sequence != other.sequence {
+		return i.sequence > other.sequence
+	}
+	return !i.stamp.Before(other.stamp)
+}
+
+// loadMail returns the events of the iCalendar attachments in the mailbox of the source.
+// Attachments are applied in the order of the mailbox: requests add or update an event and
+// cancellations remove it. Cancelling an event without RECURRENCE-ID removes all its occurrences,
+// cancelling a single occurrence excludes it from the series.
+func loadMail(source config.SourceInfo) ([]*ics.VEvent, error) {
+	messages, err := readMailbox(source.Mail.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		keys    []string
+		invites = map[string]invite{}
+	)
+	for i, message := range messages {
+		parts, err := calendarParts(message)
+		if err != nil {
+			log.Logger.Warn("Skipping message", "source", source.Name, "message", i, "error", err)
+			continue
+		}
+
+		for _, part := range parts {
+			cal, err := ics.ParseCalendar(bytes.NewReader(part))
+			if err != nil {
+				log.Logger.Warn("Skipping invitation", "source", source.Name, "message", i, "error", err)
+				continue
+			}
+
+			method := strings.ToUpper(calendarMethod(cal))
+			// replies and counter proposals come from attendees, they do not change the event
+			if method == "REPLY" || method == "COUNTER" || method == "DECLINECOUNTER" || method == "REFRESH" {
+				continue
+			}
+
+			for _, e := range cal.Events() {
+				inv := newInvite(e, method == "CANCEL")
+				key := inviteKey(e)
+				if current, ok := invites[key]; ok && !inv.newer(current) {
+					continue
+				}
+				if _, ok := invites[key]; !ok {
+					keys = append(keys, key)
+				}
+				invites[key] = inv
+			}
+		}
+	}
+
+	// a cancelled series takes its occurrences with it
+	cancelled := map[string]bool{}
+	for _, key := range keys {
+		if inv := invites[key]; inv.cancelled && !strings.Contains(key, "\x00") {
+			cancelled[key] = true
+		}
+	}
+
+	// a cancelled occurrence is excluded from its series, which would otherwise still produce it
+	for _, key := range keys {
+		uid, recurrenceId, ok := strings.Cut(key, "\x00")
+		master, found := invites[uid]
+		if inv := invites[key]; ok && inv.cancelled && found && !master.cancelled {
+			master.event.AddExdate(recurrenceId, propertyParameters(inv.event.GetProperty(ics.ComponentProperty(ics.PropertyRecurrenceId)))...)
+		}
+	}
+
+	var events []*ics.VEvent
+	for _, key := range keys {
+		uid, _, _ := strings.Cut(key, "\x00")
+		if inv := invites[key]; !inv.cancelled && !cancelled[uid] {
+			events = append(events, inv.event)
+		}
+	}
+	return events, nil
+}
+
+func newInvite(e *ics.VEvent, cancel bool) invite {
+	inv := invite{event: e, cancelled: cancel}
+	if p := e.GetProperty(ics.ComponentPropertyStatus); p != nil && strings.EqualFold(p.Value, string(ics.ObjectStatusCancelled)) {
+		inv.cancelled = true
+	}
+	if p := e.GetProperty(ics.ComponentPropertySequence); p != nil {
+		inv.sequence, _ = strconv.Atoi(strings.TrimSpace(p.Value))
+	}
+	inv.stamp, _ = e.GetDtStampTime()
+	return inv
+}
+
+// inviteKey identifies an event, occurrences of a series have their RECURRENCE-ID appended
+func inviteKey(e *ics.VEvent) string {
+	if p := e.GetProperty(ics.ComponentProperty(ics.PropertyRecurrenceId)); p != nil {
+		return e.Id() + "\x00" + p.Value
+	}
+	return e.Id()
+}
+
+// propertyParameters returns the parameters of the property, such as TZID, to copy them to another
+func propertyParameters(p *ics.IANAProperty) []ics.PropertyParameter {
+	keys := make([]string, 0, len(p.ICalParameters))
+	for key := range p.ICalParameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	params := make([]ics.PropertyParameter, 0, len(keys))
+	for _, key := range keys {
+		params = append(params, &ics.KeyValues{Key: key, Value: p.ICalParameters[key]})
+	}
+	return params
+}
+
+func calendarMethod(cal *ics.Calendar) string {
+	for _, p := range cal.CalendarProperties {
+		if p.IANAToken == string(ics.PropertyMethod) {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// readMailbox returns the raw messages in an mbox file, or in a Maildir or directory of
+// messages ordered by file name. Maildir file names start with the time of delivery.
+func readMailbox(path string) ([][]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return splitMbox(content), nil
+	}
+
+	dirs := []string{filepath.Join(path, "cur"), filepath.Join(path, "new")}
+	if _, err := os.Stat(dirs[0]); err != nil {
+		dirs = []string{path}
+	}
+
+	var files []string
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				files = append(files, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return filepath.Base(files[i]) < filepath.Base(files[j]) })
+
+	messages := make([][]byte, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, content)
+	}
+	return messages, nil
+}
+
+// quotedFrom matches body lines escaped by the mbox format, such as >From or >>From
+var quotedFrom = regexp.MustCompile(`^>+From `)
+
+// splitMbox splits an mbox file into its messages, each message starts with a "From " line
+func splitMbox(content []byte) [][]byte {
+	var (
+		messages [][]byte
+		current  *bytes.Buffer
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), len(content)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "From ") {
+			if current != nil {
+				messages = append(messages, current.Bytes())
+			}
+			current = &bytes.Buffer{}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if quotedFrom.MatchString(line) {
+			line = line[1:]
+		}
+		current.WriteString(line + "\n")
+	}
+	if current != nil {
+		messages = append(messages, current.Bytes())
+	}
+	return messages
+}
+
+// mimeHeader is the header of a message or of a part of it
+type mimeHeader interface {
+	Get(key string) string
+}
+
+// calendarParts returns the decoded text/calendar parts of the message
+func calendarParts(raw []byte) ([][]byte, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return mimeCalendarParts(msg.Header, msg.Body, true)
+}
+
+func mimeCalendarParts(header mimeHeader, body io.Reader, decodeQP bool) ([][]byte, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// messages without a content type are plain text
+		return nil, nil
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		var parts [][]byte
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return parts, nil
+			}
+			if err != nil {
+				return parts, err
+			}
+			// the multipart reader already decodes quoted-printable parts
+			found, err := mimeCalendarParts(part.Header, part, false)
+			if err != nil {
+				return parts, err
+			}
+			parts = append(parts, found...)
+		}
+	case mediaType == "message/rfc822":
+		// forwarded invitations
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		return calendarParts(content)
+	case mediaType == "text/calendar" || mediaType == "application/ics":
+		switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+		case "base64":
+			body = base64.NewDecoder(base64.StdEncoding, body)
+		case "quoted-printable":
+			if decodeQP {
+				body = quotedprintable.NewReader(body)
+			}
+		}
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{content}, nil
+	}
+	return nil, nil
+}
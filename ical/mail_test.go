@@ -0,0 +1,165 @@
+package ical
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func testInvite(method, uid string, sequence int, stamp, summary string) string {
+	return strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Example//EN",
+		"METHOD:" + method,
+		"BEGIN:VEVENT",
+		"UID:" + uid,
+		fmt.Sprintf("SEQUENCE:%d", sequence),
+		"DTSTAMP:" + stamp,
+		"DTSTART:20240304T090000Z",
+		"DTEND:20240304T100000Z",
+		"SUMMARY:" + summary,
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+}
+
+// testMessage wraps the invitation in a multipart message, base64 encoded or quoted-printable
+func testMessage(subject, invite string, base64Encoded bool) string {
+	encoding, body := "quoted-printable", invite
+	if base64Encoded {
+		encoding, body = "base64", base64.StdEncoding.EncodeToString([]byte(invite))
+	}
+	return "From: organizer@example.com\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"b1\"\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"From now on we meet on Mondays.\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/calendar; method=REQUEST; charset=UTF-8\r\n" +
+		"Content-Transfer-Encoding: " + encoding + "\r\n" +
+		"\r\n" +
+		body + "\r\n" +
+		"--b1--\r\n"
+}
+
+func mailSummaries(events []*ics.VEvent) []string {
+	summaries := make([]string, len(events))
+	for i, e := range events {
+		summaries[i] = e.GetProperty(ics.ComponentPropertySummary).Value
+	}
+	return summaries
+}
+
+func TestLoadMailMbox(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+
+	messages := []string{
+		testMessage("Kickoff", testInvite("REQUEST", "kickoff", 0, "20240201T100000Z", "Kickoff"), true),
+		testMessage("Lunch", testInvite("REQUEST", "lunch", 0, "20240201T100000Z", "Lunch"), false),
+		testMessage("Kickoff moved", testInvite("REQUEST", "kickoff", 1, "20240202T100000Z", "Kickoff (moved)"), true),
+		// delivered late, the newer sequence still wins
+		testMessage("Kickoff", testInvite("REQUEST", "kickoff", 0, "20240201T100000Z", "Kickoff (old)"), false),
+		testMessage("Lunch cancelled", testInvite("CANCEL", "lunch", 1, "20240203T100000Z", "Lunch"), true),
+		testMessage("Accepted", testInvite("REPLY", "review", 0, "20240203T100000Z", "Review"), true),
+		"From: friend@example.com\r\nSubject: Hi\r\n\r\n>From the beach, without invitation\r\n",
+	}
+	var mbox strings.Builder
+	for _, message := range messages {
+		// mbox writers quote body lines starting with From, and those already quoted
+		message = regexp.MustCompile(`(?m)^(>*From )`).ReplaceAllString(message, ">$1")
+		mbox.WriteString("From sender@example.com Thu Feb  1 10:00:00 2024\n" + message + "\n")
+	}
+
+	path := filepath.Join(t.TempDir(), "inbox.mbox")
+	assert.NoError(t, os.WriteFile(path, []byte(mbox.String()), 0o644))
+
+	events, err := loadMail(config.SourceInfo{Name: "Mail", Type: config.SourceMail, Mail: &config.Mail{Path: path}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Kickoff (moved)"}, mailSummaries(events))
+}
+
+func TestLoadMailMaildir(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+
+	dir := t.TempDir()
+	for sub, files := range map[string]map[string]string{
+		"cur": {
+			"1706781600.1.host:2,S": testMessage("Review", testInvite("REQUEST", "review", 0, "20240201T100000Z", "Review"), true),
+		},
+		"new": {
+			"1706868000.2.host": testMessage("Review moved", testInvite("REQUEST", "review", 0, "20240202T100000Z", "Review (moved)"), false),
+			"1706954400.3.host": testMessage("Demo", testInvite("REQUEST", "demo", 0, "20240203T100000Z", "Demo"), false),
+		},
+		"tmp": {
+			"1707040800.4.host": testMessage("Partial", testInvite("REQUEST", "partial", 0, "20240204T100000Z", "Partial"), false),
+		},
+	} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, sub), 0o755))
+		for name, content := range files {
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, sub, name), []byte(content), 0o644))
+		}
+	}
+
+	events, err := loadMail(config.SourceInfo{Name: "Mail", Type: config.SourceMail, Mail: &config.Mail{Path: dir}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Review (moved)", "Demo"}, mailSummaries(events))
+}
+
+func TestLoadMailCancelSeries(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+
+	occurrence := strings.Replace(testInvite("REQUEST", "weekly", 1, "20240202T100000Z", "Weekly (moved)"),
+		"SEQUENCE:1", "SEQUENCE:1\r\nRECURRENCE-ID:20240311T090000Z", 1)
+	dir := t.TempDir()
+	for i, message := range []string{
+		testMessage("Weekly", testInvite("REQUEST", "weekly", 0, "20240201T100000Z", "Weekly"), true),
+		testMessage("Weekly moved", occurrence, true),
+		testMessage("Weekly cancelled", testInvite("CANCEL", "weekly", 2, "20240203T100000Z", "Weekly"), true),
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.eml", i)), []byte(message), 0o644))
+	}
+
+	events, err := loadMail(config.SourceInfo{Name: "Mail", Type: config.SourceMail, Mail: &config.Mail{Path: dir}})
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestLoadMailCancelOccurrence(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+
+	series := strings.Replace(testInvite("REQUEST", "weekly", 0, "20240201T100000Z", "Weekly"),
+		"DTEND:20240304T100000Z", "DTEND:20240304T100000Z\r\nRRULE:FREQ=WEEKLY", 1)
+	occurrence := strings.Replace(testInvite("CANCEL", "weekly", 1, "20240202T100000Z", "Weekly"),
+		"SEQUENCE:1", "SEQUENCE:1\r\nRECURRENCE-ID:20240311T090000Z", 1)
+	dir := t.TempDir()
+	for i, message := range []string{
+		testMessage("Weekly", series, true),
+		testMessage("Weekly cancelled", occurrence, false),
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.eml", i)), []byte(message), 0o644))
+	}
+
+	events, err := loadMail(config.SourceInfo{Name: "Mail", Type: config.SourceMail, Mail: &config.Mail{Path: dir}})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "FREQ=WEEKLY", events[0].GetProperty(ics.ComponentPropertyRrule).Value)
+		exdate := events[0].GetProperty(ics.ComponentPropertyExdate)
+		if assert.NotNil(t, exdate) {
+			assert.Equal(t, "20240311T090000Z", exdate.Value)
+		}
+	}
+}
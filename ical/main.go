@@ -2,8 +2,8 @@ package ical
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/Fesaa/ical-merger/config"
 	"github.com/Fesaa/ical-merger/log"
@@ -11,12 +11,18 @@ import (
 )
 
 type LoadediCal struct {
-	source       config.SourceInfo
-	events       []*ics.VEvent
-	isFiltered   bool
-	currentDay   int
-	currentMonth time.Month
-	currentYear  int
+	source     config.SourceInfo
+	events     []*ics.VEvent
+	original   []*ics.VEvent
+	parent     *CustomCalender
+	isFiltered bool
+	filtering  bool
+	dayCache   map[string]map[string]bool
+	// kept holds the events passing the rules while modifiers are applied
+	kept   []*ics.VEvent
+	firsts *positionalIndex
+	// deferred holds the structural actions to apply once all events have been modified
+	deferred []deferredAction
 }
 
 func (c *LoadediCal) Events() []*ics.VEvent {
@@ -35,58 +41,117 @@ func (c *LoadediCal) FilteredEvents() []*ics.VEvent {
 	return c.events
 }
 
-func (c *LoadediCal) Modify(e *ics.VEvent) *ics.VEvent {
-	modifiers := c.Source().Modifiers
-	if len(modifiers) == 0 {
-		return e
+// scope returns the events positional checks compare against
+func (c *LoadediCal) scope() []*ics.VEvent {
+	if c.kept != nil {
+		return c.kept
 	}
+	return c.events
+}
+
+func (c *LoadediCal) Modify(e *ics.VEvent) *ics.VEvent {
+	c.applyModifiers(c.Source().Modifiers, e)
+	return e
+}
 
-	for _, modifier := range modifiers {
-		for _, filter := range modifier.Filters {
-			if !c.apply(&filter, e) {
-				return e
+// applyModifiers applies every modifier whose filters all pass, and the else branch of
+// those that do not. It returns false once a modifier with flow stop has been applied.
+func (c *LoadediCal) applyModifiers(modifiers []config.Modifier, e *ics.VEvent) bool {
+	for i := range modifiers {
+		modifier := &modifiers[i]
+		if !c.matchesAll(modifier.Filters, e) {
+			if !c.applyModifiers(modifier.Else, e) {
+				return false
 			}
+			continue
 		}
 
-		prop := ics.ComponentProperty(modifier.Component)
-		comp := e.GetProperty(prop)
-		switch modifier.Action {
-		case config.APPEND:
-			comp.Value += modifier.Data
-		case config.PREPEND:
-			comp.Value = modifier.Data + comp.Value
-		case config.REPLACE:
-			comp.Value = modifier.Data
-		case config.ALARM:
-			a := e.AddAlarm()
-			a.SetAction(ics.ActionDisplay)
-			a.SetTrigger(modifier.Data)
-			a.SetProperty(ics.ComponentPropertyDescription, modifier.Name)
+		c.applyModifier(modifier, e)
+		if modifier.Flow == config.FlowStop {
+			return false
 		}
-		if modifier.Action != config.ALARM {
-			e.SetProperty(prop, comp.Value)
+	}
+	return true
+}
+
+func (c *LoadediCal) matchesAll(rules []config.Rule, e *ics.VEvent) bool {
+	for i := range rules {
+		if !c.apply(&rules[i], e) {
+			return false
 		}
 	}
-	return e
+	return true
+}
+
+func (c *LoadediCal) applyModifier(modifier *config.Modifier, e *ics.VEvent) {
+	action, ok := lookupAction(modifier.Action)
+	if !ok {
+		log.Logger.Warn("Action not found", "modifier_name", modifier.Name, "action", modifier.Action)
+		return
+	}
+	err := action(e, ModifierContext{Modifier: modifier, Calendar: c})
+	switch {
+	case errors.Is(err, errMissingProperty):
+		log.Logger.Debug("Skipped modifier", "modifier_name", modifier.Name, "event_id", e.Id(), "error", err)
+	case err != nil:
+		log.Logger.Warn("Failed to apply modifier", "modifier_name", modifier.Name, "event_id", e.Id(), "error", err)
+	}
 }
 
 func (c *LoadediCal) Filter() {
 	if c.isFiltered {
 		log.Logger.Warn("Filtering an already filtered calendar", "sourceName", c.source.Name)
 	}
-	var filtered []*ics.VEvent
+	filtered := []*ics.VEvent{}
+	c.filtering = true
+	nextFilePass()
+	defer func() { c.filtering = false }()
 
 	for _, event := range c.events {
 		if c.Check(event) {
-			event := c.Modify(event)
 			filtered = append(filtered, event)
 		}
 	}
+
+	// modifiers only see the events kept by the rules
+	c.kept, c.firsts = filtered, nil
+	for i, event := range filtered {
+		filtered[i] = c.Modify(event)
+	}
+	filtered = c.restructure(filtered)
+	// stamped last, so events added or split by the structural modifiers are marked as well
+	for _, event := range filtered {
+		stamp(event, c.source)
+	}
+
 	c.events = filtered
+	c.kept, c.firsts = nil, nil
 	c.isFiltered = true
 }
 
+func init() {
+	// the config package knows URL sources already, only their loader is registered here
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	sourceFuncs[config.SourceUrl] = loadUrl
+}
+
+// NewLoadediCal loads the events of the source using the loader of its type
 func NewLoadediCal(source config.SourceInfo) (*LoadediCal, error) {
+	load, ok := lookupSource(source.Kind())
+	if !ok {
+		return nil, fmt.Errorf("unknown source type %q", source.Kind())
+	}
+
+	events, err := load(source)
+	if err != nil {
+		return nil, err
+	}
+	return &LoadediCal{source: source, events: events, original: events, isFiltered: false}, nil
+}
+
+// loadUrl loads the events of the calendar at the URL of the source, in the format of the source
+func loadUrl(source config.SourceInfo) ([]*ics.VEvent, error) {
 	res, e := http.Get(source.Url)
 	if e != nil {
 		return nil, e
@@ -97,9 +162,5 @@ func NewLoadediCal(source config.SourceInfo) (*LoadediCal, error) {
 	}
 	defer res.Body.Close()
 
-	cal, err := ics.ParseCalendar(res.Body)
-	if err != nil {
-		return nil, err
-	}
-	return &LoadediCal{source: source, events: cal.Events(), isFiltered: false, currentDay: -1, currentMonth: -1, currentYear: -1}, nil
+	return parseFormat(source, res.Body)
 }
@@ -0,0 +1,123 @@
+package ical
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	RegisterAction(config.MAP, actionMap, config.RequireModifierComponent, config.RequireModifierMap)
+}
+
+// actionMap rewrites every instance of the component through the lookup table of the modifier
+func actionMap(e *ics.VEvent, ctx ModifierContext) error {
+	m := ctx.Modifier.Map
+	var table config.MapEntries
+	if m.File != "" {
+		table = mapTables.load(m.File)
+	}
+
+	prop := string(ctx.Modifier.Component)
+	found := false
+	for i := range e.Properties {
+		p := &e.Properties[i]
+		if p.IANAToken != prop {
+			continue
+		}
+		found = true
+
+		value, ok, err := lookup(m.Match, ics.FromText(p.Value), m.Entries, table)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if m.Fallback == "" {
+				continue
+			}
+			value = m.Fallback
+		}
+		p.Value = ics.ToText(value)
+	}
+
+	if !found {
+		return missingProperty(ics.ComponentProperty(prop))
+	}
+	return nil
+}
+
+// lookup returns the value s maps to in the tables, the first matching entry wins. With prefix
+// matching the longest prefix wins.
+func lookup(match config.MapMatch, s string, tables ...config.MapEntries) (string, bool, error) {
+	switch match {
+	case config.MapPrefix:
+		var best *config.MapEntry
+		for _, entries := range tables {
+			for i, entry := range entries {
+				if strings.HasPrefix(s, entry.Key) && (best == nil || len(entry.Key) > len(best.Key)) {
+					best = &entries[i]
+				}
+			}
+		}
+		if best == nil {
+			return "", false, nil
+		}
+		return best.Value, true, nil
+	case config.MapRegex:
+		for _, entries := range tables {
+			for _, entry := range entries {
+				re, err := compilePattern(entry.Key)
+				if err != nil {
+					return "", false, err
+				}
+				if loc := re.FindStringSubmatchIndex(s); loc != nil {
+					return string(re.ExpandString(nil, entry.Value, s, loc)), true, nil
+				}
+			}
+		}
+		return "", false, nil
+	default:
+		for _, entries := range tables {
+			for _, entry := range entries {
+				if entry.Key == s {
+					return entry.Value, true, nil
+				}
+			}
+		}
+		return "", false, nil
+	}
+}
+
+// mapTables keeps the lookup tables of MAP modifiers in memory
+var mapTables = newFileCache("lookup table", parseMapTable)
+
+// parseMapTable parses a CSV file of key,value lines, or a YAML or JSON mapping
+func parseMapTable(content []byte, file string) (config.MapEntries, error) {
+	var entries config.MapEntries
+	if strings.ToLower(path.Ext(file)) != ".csv" {
+		err := yaml.Unmarshal(content, &entries)
+		return entries, err
+	}
+
+	r := csv.NewReader(bytes.NewReader(content))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %q does not have a key and a value", strings.Join(record, ","))
+		}
+		entries = append(entries, config.MapEntry{Key: record[0], Value: record[1]})
+	}
+	return entries, nil
+}
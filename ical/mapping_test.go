@@ -0,0 +1,72 @@
+package ical
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionMap(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	file := filepath.Join(t.TempDir(), "courses.csv")
+	assert.NoError(t, os.WriteFile(file, []byte("# code,name\nWISB123,Linear Algebra\nWISB124, \"Analysis, part 2\"\n"), 0644))
+
+	mapping := &config.Mapping{
+		File:    file,
+		Entries: config.MapEntries{{Key: "WISB124", Value: "Calculus"}},
+	}
+	modifier := config.Modifier{Component: "SUMMARY", Action: config.MAP, Map: mapping}
+
+	e := applyAction(t, newEventWithProperty(ics.ComponentPropertySummary, "WISB123"), modifier)
+	assert.Equal(t, "Linear Algebra", e.GetProperty(ics.ComponentPropertySummary).Value)
+
+	// inline entries come first
+	e = applyAction(t, newEventWithProperty(ics.ComponentPropertySummary, "WISB124"), modifier)
+	assert.Equal(t, "Calculus", e.GetProperty(ics.ComponentPropertySummary).Value)
+
+	e = applyAction(t, newEventWithProperty(ics.ComponentPropertySummary, "WISB999"), modifier)
+	assert.Equal(t, "WISB999", e.GetProperty(ics.ComponentPropertySummary).Value)
+
+	mapping.Fallback = "Unknown course"
+	e = applyAction(t, newEventWithProperty(ics.ComponentPropertySummary, "WISB999"), modifier)
+	assert.Equal(t, "Unknown course", e.GetProperty(ics.ComponentPropertySummary).Value)
+
+	// the table is checked for changes once per pass
+	mapping.Entries, mapping.Fallback = nil, ""
+	assert.NoError(t, os.WriteFile(file, []byte("WISB124,\"Analysis, part 2\"\n"), 0644))
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(file, later, later))
+	e = applyAction(t, newEventWithProperty(ics.ComponentPropertySummary, "WISB123"), modifier)
+	assert.Equal(t, "Linear Algebra", e.GetProperty(ics.ComponentPropertySummary).Value)
+
+	nextFilePass()
+	e = applyAction(t, newEventWithProperty(ics.ComponentPropertySummary, "WISB124"), modifier)
+	assert.Equal(t, `Analysis\, part 2`, e.GetProperty(ics.ComponentPropertySummary).Value)
+}
+
+func TestActionMapMatch(t *testing.T) {
+	rooms := config.Modifier{Component: "LOCATION", Action: config.MAP, Map: &config.Mapping{
+		Match: config.MapRegex,
+		Entries: config.MapEntries{
+			{Key: `^A-(\d)\.(\d+)$`, Value: "Building A, floor $1, room $2"},
+			{Key: `^A-`, Value: "Building A"},
+		},
+	}}
+	e := applyAction(t, newEventWithProperty(ics.ComponentPropertyLocation, "A-0.12"), rooms)
+	assert.Equal(t, `Building A\, floor 0\, room 12`, e.GetProperty(ics.ComponentPropertyLocation).Value)
+	e = applyAction(t, newEventWithProperty(ics.ComponentPropertyLocation, "A-Hall"), rooms)
+	assert.Equal(t, "Building A", e.GetProperty(ics.ComponentPropertyLocation).Value)
+
+	courses := config.Modifier{Component: "SUMMARY", Action: config.MAP, Map: &config.Mapping{
+		Match:   config.MapPrefix,
+		Entries: config.MapEntries{{Key: "WIS", Value: "Mathematics"}, {Key: "WISB1", Value: "First year mathematics"}},
+	}}
+	e = applyAction(t, newEventWithProperty(ics.ComponentPropertySummary, "WISB123"), courses)
+	assert.Equal(t, "First year mathematics", e.GetProperty(ics.ComponentPropertySummary).Value)
+}
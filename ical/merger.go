@@ -10,8 +10,11 @@ import (
 )
 
 type CustomCalender struct {
-	source config.Source
-	loaded []*LoadediCal
+	source    config.Source
+	loaded    []*LoadediCal
+	conflicts []Conflict
+	// data holds the last successfully loaded external rule data by location
+	data map[string][]string
 }
 
 func FromSource(source config.Source) CustomCalender {
@@ -22,16 +25,22 @@ func (c *CustomCalender) GetSource() config.Source {
 	return c.source
 }
 
+// Conflicts returns the overlapping events found during the last merge
+func (c *CustomCalender) Conflicts() []Conflict {
+	return c.conflicts
+}
+
 func (c *CustomCalender) Merge() (*ics.Calendar, error) {
 	var cals []*LoadediCal
 	for _, source := range c.source.Info {
-		cal, er := NewLoadediCal(source)
+		cal, er := NewLoadediCal(c.resolveData(source))
 		if er != nil {
 			log.Logger.Error("Error loading source", "source_name", source.Name, "error", er)
-			log.Logger.Notify(fmt.Sprintf("[%s] Could not complete request, error loading %s", c.source.Name, source.Name+er.Error()))
+			log.Logger.Notify(fmt.Sprintf("[%s] Could not complete request, error loading %s", c.source.Name, source.Name+": "+er.Error()))
 			return nil, er
 		}
 		log.Logger.Info("Loaded events", "events", len(cal.Events()), "source", cal.Source().Name)
+		cal.parent = c
 		cals = append(cals, cal)
 	}
 
@@ -40,23 +49,73 @@ func (c *CustomCalender) Merge() (*ics.Calendar, error) {
 	return c.mergeLoadediCals(), nil
 }
 
+// filterEndpoint applies the rules and modifiers of the endpoint to the events of all sources
+func (c *CustomCalender) filterEndpoint(events []*ics.VEvent) []*ics.VEvent {
+	if len(c.source.Rules) == 0 && len(c.source.Modifiers) == 0 {
+		return events
+	}
+
+	merged := &LoadediCal{
+		source: config.SourceInfo{
+			Name:      c.source.Name,
+			Rules:     c.resolveRules(c.source.Rules),
+			Modifiers: c.resolveModifiers(c.source.Modifiers),
+		},
+		events:   events,
+		original: events,
+		parent:   c,
+	}
+	filtered := merged.FilteredEvents()
+	log.Logger.Info("Filtered merged events", "events", len(filtered), "removed", len(events)-len(filtered), "source", c.source.Name)
+	return filtered
+}
+
 func (c *CustomCalender) mergeLoadediCals() *ics.Calendar {
 	calender := ics.NewCalendar()
-	calender.SetXWRCalName(c.source.Name)
 
-	var XWRDesc string = ""
+	var (
+		XWRDesc string = ""
+		events  []*ics.VEvent
+		sources = map[*ics.VEvent]string{}
+		spans   []spanEvent
+	)
+	// Sources depended on by ON_DAY_OF and OVERLAPS_WITH rules are filtered on demand, before the dependent source
 	for _, iCal := range c.loaded {
-		events := iCal.FilteredEvents()
+		filtered := iCal.FilteredEvents()
+		privacy := c.privacy(iCal.Source())
 
 		XWRDesc += iCal.Source().Name + " "
-		log.Logger.Info("Adding events ", "events", len(events), "source", iCal.Source().Name)
-		for _, event := range events {
-			log.Logger.Debug("Adding event", "event_id", event.Id())
-			calender.AddVEvent(event)
+		log.Logger.Info("Adding events ", "events", len(filtered), "source", iCal.Source().Name)
+		for _, event := range filtered {
+			event, ok := applyPrivacy(event, privacy, iCal.Source().Categories)
+			if !ok {
+				log.Logger.Debug("Hiding event", "source", iCal.Source().Name)
+				continue
+			}
+			events = append(events, event)
+			sources[event] = iCal.Source().Name
 		}
 	}
 
-	calender.SetXWRCalDesc(strings.TrimSuffix(XWRDesc, " "))
+	events = c.filterEndpoint(events)
+
+	for _, event := range events {
+		log.Logger.Debug("Adding event", "event_id", event.Id())
+		calender.AddVEvent(event)
+		if !c.source.Conflicts.Enabled {
+			continue
+		}
+		if se, ok := newSpanEvent(sources[event], event); ok {
+			spans = append(spans, se)
+		}
+	}
+
+	if c.source.Conflicts.Enabled {
+		c.conflicts = findConflicts(spans, c.source.Conflicts.SameSource)
+		log.Logger.Info("Found conflicts", "conflicts", len(c.conflicts), "source", c.source.Name)
+	}
+
+	c.setMetadata(calender, strings.TrimSuffix(XWRDesc, " "))
 
 	return calender
 }
@@ -0,0 +1,90 @@
+package ical
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+)
+
+// stamp marks the event with the color, categories and tag of the source it came from
+func stamp(e *ics.VEvent, source config.SourceInfo) {
+	if source.Color != "" {
+		e.SetColor(strings.ToLower(source.Color))
+	}
+
+	var existing []string
+	for _, p := range e.Properties {
+		if p.IANAToken == string(ics.ComponentPropertyCategories) {
+			existing = append(existing, strings.Split(p.Value, ",")...)
+		}
+	}
+	var categories []string
+	for _, category := range source.Categories {
+		if !slices.Contains(existing, category) && !slices.Contains(categories, category) {
+			categories = append(categories, category)
+		}
+	}
+	if len(categories) > 0 {
+		e.AddCategory(strings.Join(categories, ","))
+	}
+
+	if source.Tag != "" {
+		if p := e.GetProperty(ics.ComponentPropertySummary); p != nil {
+			p.Value = fmt.Sprintf("[%s] %s", ics.ToText(source.Tag), p.Value)
+		}
+	}
+}
+
+// setMetadata sets the RFC 7986 properties of the endpoint on the calendar, next to the
+// X-WR properties older clients use
+func (c *CustomCalender) setMetadata(calender *ics.Calendar, description string) {
+	meta := c.source.Calendar
+
+	name := meta.Name
+	if name == "" {
+		name = c.source.Name
+	}
+	calender.SetXWRCalName(name)
+	if name != "" {
+		calender.SetName(name)
+	}
+
+	if meta.Description != "" {
+		description = meta.Description
+		calender.SetDescription(description)
+	}
+	calender.SetXWRCalDesc(description)
+
+	if meta.Color != "" {
+		calender.SetColor(strings.ToLower(meta.Color))
+	}
+
+	refresh := meta.RefreshInterval
+	if refresh == "" && c.source.Heartbeat > 0 {
+		refresh = fmt.Sprintf("PT%dM", c.source.Heartbeat)
+	}
+	if refresh != "" {
+		// RFC 7986 requires VALUE=DURATION, golang-ical only has it as part of the property name
+		calender.CalendarProperties = append(calender.CalendarProperties, ics.CalendarProperty{
+			BaseProperty: ics.BaseProperty{
+				IANAToken:      "REFRESH-INTERVAL",
+				ICalParameters: map[string][]string{string(ics.ParameterValue): {"DURATION"}},
+				Value:          refresh,
+			},
+		})
+		calender.SetXPublishedTTL(refresh)
+	}
+
+	if meta.Image != "" {
+		calender.CalendarProperties = append(calender.CalendarProperties, ics.CalendarProperty{
+			BaseProperty: ics.BaseProperty{
+				IANAToken:      "IMAGE",
+				ICalParameters: map[string][]string{string(ics.ParameterValue): {"URI"}},
+				Value:          meta.Image,
+			},
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStamp(t *testing.T) {
+	e := newEventWithProperty(ics.ComponentPropertySummary, "Lecture")
+	e.AddCategory("School,Work")
+
+	stamp(e, config.SourceInfo{Color: "DarkRed", Categories: []string{"Work", "Uni"}, Tag: "Uni"})
+	assert.Equal(t, "darkred", e.GetProperty(ics.ComponentPropertyColor).Value)
+	assert.Equal(t, "[Uni] Lecture", e.GetProperty(ics.ComponentPropertySummary).Value)
+
+	var categories []string
+	for _, p := range e.Properties {
+		if p.IANAToken == string(ics.ComponentPropertyCategories) {
+			categories = append(categories, p.Value)
+		}
+	}
+	assert.Equal(t, []string{"School,Work", "Uni"}, categories)
+}
+
+func TestStampSyntheticEvents(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)
+
+	cal := newLoadedCal("school",
+		newEventAt("lecture", "Building A", day.Add(9*time.Hour), time.Hour),
+		newEventAt("lab", "Building B", day.Add(12*time.Hour), time.Hour),
+	)
+	cal.source.Color = "Teal"
+	cal.source.Categories = []string{"School"}
+	cal.source.Modifiers = []config.Modifier{{Action: config.TRAVEL, Data: "PT20M"}}
+	cal.Filter()
+
+	events := cal.Events()
+	if assert.Len(t, events, 4) {
+		for _, e := range events {
+			assert.Equal(t, "teal", e.GetProperty(ics.ComponentPropertyColor).Value, e.Id())
+			assert.Equal(t, "School", e.GetProperty(ics.ComponentPropertyCategories).Value, e.Id())
+		}
+	}
+}
+
+func TestCalendarMetadata(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+
+	c := &CustomCalender{source: config.Source{
+		Name:      "Merged",
+		Heartbeat: 30,
+		Calendar: config.CalendarMetadata{
+			Description: "All my calendars",
+			Color:       "teal",
+			Image:       "https://example.com/logo.png",
+		},
+	}}
+
+	out := c.mergeLoadediCals().Serialize()
+	for _, line := range []string{
+		"X-WR-CALNAME:Merged",
+		"NAME:Merged",
+		"DESCRIPTION:All my calendars",
+		"X-WR-CALDESC:All my calendars",
+		"COLOR:teal",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT30M",
+		"IMAGE;VALUE=URI:https://example.com/logo.png",
+	} {
+		assert.True(t, strings.Contains(out, line+"\r\n"), line)
+	}
+
+	// the value type survives parsing, clients following RFC 7986 require it
+	parsed, err := ics.ParseCalendar(strings.NewReader(out))
+	assert.NoError(t, err)
+	var refresh *ics.CalendarProperty
+	for i, p := range parsed.CalendarProperties {
+		if p.IANAToken == "REFRESH-INTERVAL" {
+			refresh = &parsed.CalendarProperties[i]
+		}
+	}
+	if assert.NotNil(t, refresh) {
+		assert.Equal(t, "PT30M", refresh.Value)
+		assert.Equal(t, []string{"DURATION"}, refresh.ICalParameters[string(ics.ParameterValue)])
+	}
+}
@@ -0,0 +1,125 @@
+package ical
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+)
+
+// detailProperties are removed from events published as title or busy
+var detailProperties = []ics.ComponentProperty{
+	ics.ComponentPropertyDescription,
+	ics.ComponentPropertyLocation,
+	ics.ComponentPropertyGeo,
+	ics.ComponentPropertyAttendee,
+	ics.ComponentPropertyOrganizer,
+	ics.ComponentPropertyAttach,
+	ics.ComponentPropertyUrl,
+	ics.ComponentPropertyComment,
+	ics.ComponentProperty(ics.PropertyContact),
+	ics.ComponentPropertyResources,
+	ics.ComponentProperty("CONFERENCE"),
+}
+
+// privacy returns the privacy settings of the source, the fields it leaves empty fall back
+// to those of the endpoint
+func (c *CustomCalender) privacy(source config.SourceInfo) config.Privacy {
+	if source.Privacy != nil {
+		return source.Privacy.Inherit(c.source.Privacy)
+	}
+	return c.source.Privacy
+}
+
+// applyPrivacy returns the event as it may be published under the privacy settings, or
+// false when it must not be published at all. Events are copied before being changed, as
+// other sources may still look at the original. Busy events keep only the categories of
+// the source, so the source can still be told apart.
+func applyPrivacy(e *ics.VEvent, privacy config.Privacy, sourceCategories []string) (*ics.VEvent, bool) {
+	level := privacyLevel(e, privacy)
+	switch level {
+	case config.PrivacyHidden:
+		return nil, false
+	case config.PrivacyTitle, config.PrivacyBusy:
+	default:
+		return e, true
+	}
+
+	private := cloneEvent(e)
+	for _, prop := range detailProperties {
+		removeProperty(&private.ComponentBase, prop)
+	}
+	// clients keep all kinds of details in extension properties, and alarms may describe the event as well
+	private.Properties = slices.DeleteFunc(private.Properties, func(p ics.IANAProperty) bool {
+		return strings.HasPrefix(strings.ToUpper(p.IANAToken), "X-")
+	})
+	private.Components = slices.DeleteFunc(private.Components, func(c ics.Component) bool {
+		_, ok := c.(*ics.VAlarm)
+		return ok
+	})
+
+	if level == config.PrivacyBusy {
+		label := privacy.Label
+		if label == "" {
+			label = config.DefaultBusyLabel
+		}
+		private.SetSummary(label)
+		keepCategories(private, sourceCategories)
+	}
+	return private, true
+}
+
+// privacyLevel returns the level the event is published at, private events are published at
+// least at the private level
+func privacyLevel(e *ics.VEvent, privacy config.Privacy) config.PrivacyLevel {
+	level := privacy.Level
+	if p := e.GetProperty(ics.ComponentPropertyClass); p != nil {
+		switch strings.ToUpper(p.Value) {
+		case string(ics.ClassificationPrivate), string(ics.ClassificationConfidential):
+			private := privacy.Private
+			if private == "" {
+				private = config.PrivacyBusy
+			}
+			level = level.Stricter(private)
+		}
+	}
+	return level
+}
+
+// keepCategories removes the categories of the event that are not in keep
+func keepCategories(e *ics.VEvent, keep []string) {
+	var kept []string
+	for _, p := range e.Properties {
+		if p.IANAToken != string(ics.ComponentPropertyCategories) {
+			continue
+		}
+		for _, category := range strings.Split(p.Value, ",") {
+			if slices.Contains(keep, category) && !slices.Contains(kept, category) {
+				kept = append(kept, category)
+			}
+		}
+	}
+
+	removeProperty(&e.ComponentBase, ics.ComponentPropertyCategories)
+	if len(kept) > 0 {
+		e.AddCategory(strings.Join(kept, ","))
+	}
+}
+
+// cloneEvent returns a copy of the event whose properties can be changed without affecting
+// the original. Subcomponents are shared.
+func cloneEvent(e *ics.VEvent) *ics.VEvent {
+	properties := make([]ics.IANAProperty, len(e.Properties))
+	for i, p := range e.Properties {
+		properties[i] = p
+		properties[i].ICalParameters = make(map[string][]string, len(p.ICalParameters))
+		for key, values := range p.ICalParameters {
+			properties[i].ICalParameters[key] = slices.Clone(values)
+		}
+	}
+	return &ics.VEvent{ComponentBase: ics.ComponentBase{
+		Properties: properties,
+		Components: slices.Clone(e.Components),
+	}}
+}
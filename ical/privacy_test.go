@@ -0,0 +1,120 @@
+package ical
+
+import (
+	"testing"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDetailedEvent(id string) *ics.VEvent {
+	e := ics.NewEvent(id)
+	e.SetSummary("Dentist")
+	e.SetDescription("Bring insurance card")
+	e.SetLocation("Main Street 1")
+	e.SetURL("https://example.com")
+	e.AddAttendee("mailto:a@example.com")
+	e.SetProperty("X-ALT-DESC", "<p>Bring insurance card</p>", &ics.KeyValues{Key: "FMTTYPE", Value: []string{"text/html"}})
+	e.SetProperty("X-MICROSOFT-CDO-BUSYSTATUS", "BUSY")
+	e.AddAlarm().SetTrigger("-PT15M")
+	return e
+}
+
+func TestApplyPrivacy(t *testing.T) {
+	e := newDetailedEvent("1")
+
+	full, ok := applyPrivacy(e, config.Privacy{}, nil)
+	assert.True(t, ok)
+	assert.Same(t, e, full)
+
+	title, ok := applyPrivacy(e, config.Privacy{Level: config.PrivacyTitle}, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "Dentist", title.GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Nil(t, title.GetProperty(ics.ComponentPropertyDescription))
+	assert.Nil(t, title.GetProperty(ics.ComponentPropertyLocation))
+	assert.Nil(t, title.GetProperty(ics.ComponentPropertyUrl))
+	assert.Empty(t, title.Attendees())
+	assert.Nil(t, title.GetProperty("X-ALT-DESC"))
+	assert.Nil(t, title.GetProperty("X-MICROSOFT-CDO-BUSYSTATUS"))
+	assert.Empty(t, title.Alarms())
+
+	// parameters of the copy are not shared with the original
+	title.GetProperty(ics.ComponentPropertySummary).ICalParameters["LANGUAGE"] = []string{"en"}
+	assert.NotContains(t, e.GetProperty(ics.ComponentPropertySummary).ICalParameters, "LANGUAGE")
+
+	busy, ok := applyPrivacy(e, config.Privacy{Level: config.PrivacyBusy, Label: "Away"}, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "Away", busy.GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Nil(t, busy.GetProperty(ics.ComponentPropertyDescription))
+	for _, p := range busy.Properties {
+		assert.NotContains(t, p.IANAToken, "X-")
+	}
+	assert.Empty(t, busy.Alarms())
+
+	// busy events keep the categories of the source, not those of the event
+	e.AddCategory("Dentist,Work")
+	busy, ok = applyPrivacy(e, config.Privacy{Level: config.PrivacyBusy}, []string{"Work", "Personal"})
+	assert.True(t, ok)
+	assert.Equal(t, "Work", busy.GetProperty(ics.ComponentPropertyCategories).Value)
+	busy, _ = applyPrivacy(e, config.Privacy{Level: config.PrivacyBusy}, nil)
+	assert.Nil(t, busy.GetProperty(ics.ComponentPropertyCategories))
+	removeProperty(&e.ComponentBase, ics.ComponentPropertyCategories)
+
+	_, ok = applyPrivacy(e, config.Privacy{Level: config.PrivacyHidden}, nil)
+	assert.False(t, ok)
+
+	// the original is left untouched
+	assert.Equal(t, "Dentist", e.GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Equal(t, "Bring insurance card", e.GetProperty(ics.ComponentPropertyDescription).Value)
+	assert.Len(t, e.Attendees(), 1)
+	assert.NotNil(t, e.GetProperty("X-ALT-DESC"))
+	assert.Len(t, e.Alarms(), 1)
+}
+
+func TestApplyPrivacyClass(t *testing.T) {
+	e := newDetailedEvent("1")
+	e.SetClass(ics.ClassificationPrivate)
+
+	busy, ok := applyPrivacy(e, config.Privacy{}, nil)
+	assert.True(t, ok)
+	assert.Equal(t, config.DefaultBusyLabel, busy.GetProperty(ics.ComponentPropertySummary).Value)
+
+	// a stricter level is not loosened by the class
+	_, ok = applyPrivacy(e, config.Privacy{Level: config.PrivacyHidden, Private: config.PrivacyTitle}, nil)
+	assert.False(t, ok)
+
+	_, ok = applyPrivacy(e, config.Privacy{Private: config.PrivacyHidden}, nil)
+	assert.False(t, ok)
+}
+
+func TestMergePrivacy(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+
+	work := newLoadedCal("work", newDetailedEvent("w1"))
+	personal := newLoadedCal("personal", newDetailedEvent("p1"))
+	personal.source.Privacy = &config.Privacy{Level: config.PrivacyHidden}
+
+	c := &CustomCalender{
+		source: config.Source{Name: "shared", Privacy: config.Privacy{Level: config.PrivacyBusy}},
+		loaded: []*LoadediCal{work, personal},
+	}
+	work.parent, personal.parent = c, c
+
+	events := c.mergeLoadediCals().Events()
+	assert.Len(t, events, 1)
+	assert.Equal(t, "w1", events[0].Id())
+	assert.Equal(t, config.DefaultBusyLabel, events[0].GetProperty(ics.ComponentPropertySummary).Value)
+
+	// a source setting only a label keeps the level of the endpoint
+	personal.source.Privacy = &config.Privacy{Label: "Away"}
+	events = c.mergeLoadediCals().Events()
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "p1", events[1].Id())
+		assert.Equal(t, "Away", events[1].GetProperty(ics.ComponentPropertySummary).Value)
+		assert.Nil(t, events[1].GetProperty(ics.ComponentPropertyDescription))
+		assert.Nil(t, events[1].GetProperty(ics.ComponentPropertyLocation))
+		assert.Empty(t, events[1].Attendees())
+	}
+}
@@ -0,0 +1,108 @@
+package ical
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+)
+
+// RuleContext is passed to a check, it holds the rule being evaluated and the
+// calendar the event belongs to
+type RuleContext struct {
+	Rule     *config.Rule
+	Calendar *LoadediCal
+}
+
+// ModifierContext is passed to an action, it holds the modifier being applied and
+// the calendar the event belongs to
+type ModifierContext struct {
+	Modifier *config.Modifier
+	Calendar *LoadediCal
+}
+
+// CheckFunc reports whether the event passes the rule
+type CheckFunc func(*ics.VEvent, RuleContext) bool
+
+// ActionFunc modifies the event in place
+type ActionFunc func(*ics.VEvent, ModifierContext) error
+
+// SourceFunc loads the events of a source
+type SourceFunc func(config.SourceInfo) ([]*ics.VEvent, error)
+
+var (
+	registryLock sync.RWMutex
+	checkFuncs   = map[string]CheckFunc{}
+	actionFuncs  = map[config.Action]ActionFunc{}
+	sourceFuncs  = map[config.SourceType]SourceFunc{}
+)
+
+// RegisterCheck makes a check available under the given name, rules can then use it
+// via their check field. The optional validators are run on every rule using the check
+// when the config is loaded. It panics if fn is nil or the name is already taken.
+func RegisterCheck(name string, fn CheckFunc, validators ...config.RuleValidator) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if fn == nil {
+		panic("ical: RegisterCheck fn is nil")
+	}
+	if _, ok := checkFuncs[name]; ok {
+		panic(fmt.Sprintf("ical: RegisterCheck called twice for %s", name))
+	}
+	checkFuncs[name] = fn
+	config.RegisterCheck(name, validators...)
+}
+
+// RegisterAction makes an action available under the given name, modifiers can then
+// use it via their action field. The optional validators are run on every modifier using
+// the action when the config is loaded. It panics if fn is nil or the name is already taken.
+func RegisterAction(action config.Action, fn ActionFunc, validators ...config.ModifierValidator) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if fn == nil {
+		panic("ical: RegisterAction fn is nil")
+	}
+	if _, ok := actionFuncs[action]; ok {
+		panic(fmt.Sprintf("ical: RegisterAction called twice for %s", action))
+	}
+	actionFuncs[action] = fn
+	config.RegisterAction(action, validators...)
+}
+
+// RegisterSource makes a source type available, sources can then use it via their type
+// field. The optional validators are run on every source of the type when the config is
+// loaded. It panics if fn is nil or the type is already taken.
+func RegisterSource(t config.SourceType, fn SourceFunc, validators ...config.SourceValidator) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if fn == nil {
+		panic("ical: RegisterSource fn is nil")
+	}
+	if _, ok := sourceFuncs[t]; ok {
+		panic(fmt.Sprintf("ical: RegisterSource called twice for %s", t))
+	}
+	sourceFuncs[t] = fn
+	config.RegisterSourceType(t, validators...)
+}
+
+func lookupCheck(name string) (CheckFunc, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	fn, ok := checkFuncs[name]
+	return fn, ok
+}
+
+func lookupAction(action config.Action) (ActionFunc, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	fn, ok := actionFuncs[action]
+	return fn, ok
+}
+
+func lookupSource(t config.SourceType) (SourceFunc, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	fn, ok := sourceFuncs[t]
+	return fn, ok
+}
@@ -0,0 +1,228 @@
+package ical
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+)
+
+const (
+	defaultWeekSummary     = "Week {{.Week}}"
+	defaultTermWeekSummary = "{{.Term}} week {{.Week}}"
+)
+
+func init() {
+	RegisterSource(config.SourceStatic, loadStatic, config.RequireEvents)
+	RegisterSource(config.SourceWeeks, loadWeeks, config.RequireWeeks)
+	RegisterSource(config.SourceTerms, loadTerms, config.RequireTerms)
+}
+
+// loadStatic returns the events defined in the config. The end of all-day events is the last
+// day they take place on.
+func loadStatic(source config.SourceInfo) ([]*ics.VEvent, error) {
+	events := make([]*ics.VEvent, 0, len(source.Events))
+	for _, def := range source.Events {
+		e, err := staticEvent(source, def)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// staticEvent builds the event defined by def
+func staticEvent(source config.SourceInfo, def config.StaticEvent) (*ics.VEvent, error) {
+	start, allDay, err := config.ParseEventTime(def.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	uid := def.Uid
+	if uid == "" {
+		uid = generatedUid(source, def.Summary, def.Start)
+	}
+	e := newGeneratedEvent(uid, def.Summary, start)
+	if def.Description != "" {
+		e.SetDescription(def.Description)
+	}
+	if def.Location != "" {
+		e.SetLocation(def.Location)
+	}
+
+	var end time.Time
+	if def.End != "" {
+		if end, _, err = config.ParseEventTime(def.End); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case allDay:
+		e.SetAllDayStartAt(start)
+		if !end.IsZero() {
+			e.SetAllDayEndAt(end.AddDate(0, 0, 1))
+		} else if def.Duration == "" {
+			e.SetAllDayEndAt(start.AddDate(0, 0, 1))
+		}
+	default:
+		f := eventTimeFormat(def.Start)
+		f.set(e, ics.ComponentPropertyDtStart, start)
+		if !end.IsZero() {
+			f.set(e, ics.ComponentPropertyDtEnd, end)
+		}
+	}
+	if def.Duration != "" {
+		e.SetProperty(ics.ComponentProperty(ics.PropertyDuration), def.Duration)
+	}
+
+	if def.RRule != "" {
+		e.AddRrule(strings.TrimPrefix(def.RRule, "RRULE:"))
+	}
+	return e, nil
+}
+
+// weekData is passed to the summary template of week markers
+type weekData struct {
+	Week int
+	Year int
+	Date time.Time
+	Term string
+}
+
+// loadWeeks returns a marker on the Monday of every week in the range of the source
+func loadWeeks(source config.SourceInfo) ([]*ics.VEvent, error) {
+	from, err := time.ParseInLocation(time.DateOnly, source.Weeks.From, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	to, err := time.ParseInLocation(time.DateOnly, source.Weeks.To, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := summaryTemplate(source.Weeks.Summary, defaultWeekSummary)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*ics.VEvent
+	for day := nextMonday(from); !day.After(to); day = day.AddDate(0, 0, 7) {
+		year, week := day.ISOWeek()
+		e, err := weekMarker(source, summary, weekData{Week: week, Year: year, Date: day})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// loadTerms returns an all-day event spanning every term, and a marker at the start of every
+// week of a term when the source has weeks
+func loadTerms(source config.SourceInfo) ([]*ics.VEvent, error) {
+	var (
+		summary *template.Template
+		err     error
+	)
+	if source.Weeks != nil {
+		if summary, err = summaryTemplate(source.Weeks.Summary, defaultTermWeekSummary); err != nil {
+			return nil, err
+		}
+	}
+
+	var events []*ics.VEvent
+	for _, term := range source.Terms {
+		start, err := time.ParseInLocation(time.DateOnly, term.Start, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		end, err := time.ParseInLocation(time.DateOnly, term.End, time.Local)
+		if err != nil {
+			return nil, err
+		}
+
+		e := newGeneratedEvent(generatedUid(source, term.Name, term.Start), term.Name, start)
+		e.SetAllDayStartAt(start)
+		e.SetAllDayEndAt(end.AddDate(0, 0, 1))
+		e.SetTimeTransparency(ics.TransparencyTransparent)
+		events = append(events, e)
+
+		if summary == nil {
+			continue
+		}
+		week := 1
+		for day := start; !day.After(end); day, week = nextMonday(day.AddDate(0, 0, 1)), week+1 {
+			year, _ := day.ISOWeek()
+			marker, err := weekMarker(source, summary, weekData{Week: week, Year: year, Date: day, Term: term.Name})
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, marker)
+		}
+	}
+	return events, nil
+}
+
+func summaryTemplate(text, fallback string) (*template.Template, error) {
+	if text == "" {
+		text = fallback
+	}
+	return template.New("summary").Parse(text)
+}
+
+// weekMarker returns a transparent all-day event on the date of the week
+func weekMarker(source config.SourceInfo, summary *template.Template, data weekData) (*ics.VEvent, error) {
+	var b strings.Builder
+	if err := summary.Execute(&b, data); err != nil {
+		return nil, err
+	}
+
+	date := data.Date.Format(time.DateOnly)
+	e := newGeneratedEvent(generatedUid(source, "week", data.Term, date), b.String(), data.Date)
+	e.SetAllDayStartAt(data.Date)
+	e.SetAllDayEndAt(data.Date.AddDate(0, 0, 1))
+	e.SetTimeTransparency(ics.TransparencyTransparent)
+	return e, nil
+}
+
+// nextMonday returns t when it is a Monday, otherwise the Monday after it
+func nextMonday(t time.Time) time.Time {
+	return t.AddDate(0, 0, (8-int(t.Weekday()))%7)
+}
+
+func newGeneratedEvent(uid, summary string, start time.Time) *ics.VEvent {
+	e := ics.NewEvent(uid)
+	// DTSTAMP is derived from the event so refreshes do not look like updates
+	e.SetDtStampTime(start)
+	e.SetSummary(summary)
+	return e
+}
+
+// generatedUid derives a UID from the source and parts, so it is stable across refreshes
+func generatedUid(source config.SourceInfo, parts ...string) string {
+	h := sha1.New()
+	h.Write([]byte(source.Name))
+	for _, part := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(part))
+	}
+	return fmt.Sprintf("%s@ical-merger", hex.EncodeToString(h.Sum(nil))[:16])
+}
+
+// eventTimeFormat returns the format static event times are written in, times with a zone
+// are written in UTC and others in floating local time
+func eventTimeFormat(s string) timeFormat {
+	if _, err := time.ParseInLocation("2006-01-02T15:04:05", s, time.Local); err == nil {
+		return timeFormat{loc: time.Local}
+	}
+	if _, err := time.ParseInLocation("2006-01-02T15:04", s, time.Local); err == nil {
+		return timeFormat{loc: time.Local}
+	}
+	return timeFormat{utc: true, loc: time.UTC}
+}
@@ -0,0 +1,77 @@
+package ical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticSource(t *testing.T) {
+	source := config.SourceInfo{Name: "office", Type: config.SourceStatic, Events: []config.StaticEvent{
+		{Summary: "Office closed", Start: "2024-12-24", End: "2024-12-26"},
+		{Summary: "Stand-up", Start: "2024-03-04T09:00", Duration: "PT15M", RRule: "FREQ=WEEKLY;BYDAY=MO"},
+		{Uid: "fixed", Summary: "Launch", Start: "2024-03-04T09:00:00Z", End: "2024-03-04T10:00:00Z"},
+	}}
+
+	cal, err := NewLoadediCal(source)
+	assert.NoError(t, err)
+	events := cal.Events()
+	assert.Len(t, events, 3)
+
+	start, end, err := eventSpan(events[0])
+	assert.NoError(t, err)
+	assert.True(t, isAllDay(events[0]))
+	assert.Equal(t, time.Date(2024, 12, 24, 0, 0, 0, 0, time.Local), start.In(time.Local))
+	assert.Equal(t, time.Date(2024, 12, 27, 0, 0, 0, 0, time.Local), end.In(time.Local))
+
+	assert.Equal(t, "20240304T090000", events[1].GetProperty(ics.ComponentPropertyDtStart).Value)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO", events[1].GetProperty(ics.ComponentPropertyRrule).Value)
+	assert.Equal(t, "PT15M", events[1].GetProperty(ics.ComponentProperty(ics.PropertyDuration)).Value)
+
+	assert.Equal(t, "fixed", events[2].Id())
+	assert.Equal(t, "20240304T100000Z", events[2].GetProperty(ics.ComponentPropertyDtEnd).Value)
+
+	// generated UIDs are stable
+	again, err := NewLoadediCal(source)
+	assert.NoError(t, err)
+	assert.Equal(t, events[0].Id(), again.Events()[0].Id())
+	assert.NotEqual(t, events[0].Id(), events[1].Id())
+}
+
+func TestWeeksSource(t *testing.T) {
+	cal, err := NewLoadediCal(config.SourceInfo{Name: "weeks", Type: config.SourceWeeks, Weeks: &config.Weeks{
+		From: "2024-03-13",
+		To:   "2024-04-01",
+	}})
+	assert.NoError(t, err)
+
+	var summaries []string
+	for _, e := range cal.Events() {
+		summaries = append(summaries, e.GetProperty(ics.ComponentPropertySummary).Value)
+		assert.Equal(t, "TRANSPARENT", e.GetProperty(ics.ComponentPropertyTransp).Value)
+	}
+	assert.Equal(t, []string{"Week 12", "Week 13", "Week 14"}, summaries)
+	assert.Equal(t, "20240318", cal.Events()[0].GetProperty(ics.ComponentPropertyDtStart).Value)
+}
+
+func TestTermsSource(t *testing.T) {
+	cal, err := NewLoadediCal(config.SourceInfo{
+		Name:  "terms",
+		Type:  config.SourceTerms,
+		Terms: []config.Term{{Name: "Term 1", Start: "2024-09-04", End: "2024-09-20"}},
+		Weeks: &config.Weeks{},
+	})
+	assert.NoError(t, err)
+
+	var summaries, starts []string
+	for _, e := range cal.Events() {
+		summaries = append(summaries, e.GetProperty(ics.ComponentPropertySummary).Value)
+		starts = append(starts, e.GetProperty(ics.ComponentPropertyDtStart).Value)
+	}
+	assert.Equal(t, []string{"Term 1", "Term 1 week 1", "Term 1 week 2", "Term 1 week 3"}, summaries)
+	assert.Equal(t, []string{"20240904", "20240904", "20240909", "20240916"}, starts)
+	assert.Equal(t, "20240921", cal.Events()[0].GetProperty(ics.ComponentPropertyDtEnd).Value)
+}
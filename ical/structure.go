@@ -0,0 +1,186 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+)
+
+func init() {
+	RegisterAction(config.SPLIT, deferStructural)
+	RegisterAction(config.COALESCE, deferStructural, config.OptionalModifierDuration)
+}
+
+// structuralFunc changes the set of events, group holds the events the modifier applies to
+type structuralFunc func(events, group []*ics.VEvent, m *config.Modifier) []*ics.VEvent
+
+var structuralActions = map[config.Action]structuralFunc{
+	config.SPLIT:    splitEvents,
+	config.COALESCE: coalesceEvents,
+}
+
+type deferredAction struct {
+	modifier *config.Modifier
+	events   []*ics.VEvent
+}
+
+// deferStructural remembers the event, structural actions run once all events have passed
+// through the modifiers so they see the final events
+func deferStructural(e *ics.VEvent, ctx ModifierContext) error {
+	if ctx.Calendar == nil {
+		return errors.New("structural actions require a calendar")
+	}
+	ctx.Calendar.deferAction(ctx.Modifier, e)
+	return nil
+}
+
+func (c *LoadediCal) deferAction(m *config.Modifier, e *ics.VEvent) {
+	for i := range c.deferred {
+		if c.deferred[i].modifier == m {
+			c.deferred[i].events = append(c.deferred[i].events, e)
+			return
+		}
+	}
+	c.deferred = append(c.deferred, deferredAction{modifier: m, events: []*ics.VEvent{e}})
+}
+
+// restructure applies the deferred structural actions in the order they were first used
+func (c *LoadediCal) restructure(events []*ics.VEvent) []*ics.VEvent {
+	for _, d := range c.deferred {
+		fn, ok := structuralActions[d.modifier.Action]
+		if !ok {
+			continue
+		}
+		events = fn(events, d.events, d.modifier)
+	}
+	c.deferred = nil
+	return events
+}
+
+// splitEvents replaces the timed events in group crossing midnight by one event per day.
+// The first piece keeps the UID of the event, the others get it suffixed by their number and
+// are related to the first by RELATED-TO.
+func splitEvents(events, group []*ics.VEvent, m *config.Modifier) []*ics.VEvent {
+	for _, e := range group {
+		i := slices.Index(events, e)
+		if i < 0 || isAllDay(e) {
+			continue
+		}
+		if e.GetProperty(ics.ComponentPropertyRrule) != nil {
+			log.Logger.Debug("Not splitting recurring event", "modifier_name", m.Name, "event_id", e.Id())
+			continue
+		}
+
+		start, end, err := eventSpan(e)
+		if err != nil {
+			log.Logger.Warn("Failed to apply modifier", "modifier_name", m.Name, "event_id", e.Id(), "error", err)
+			continue
+		}
+
+		pieces := splitEvent(e, wallClock(start), wallClock(end))
+		if len(pieces) > 1 {
+			events = slices.Replace(events, i, i+1, pieces...)
+		}
+	}
+	return events
+}
+
+func splitEvent(e *ics.VEvent, start, end time.Time) []*ics.VEvent {
+	var bounds []time.Time
+	for day := midnight(start).AddDate(0, 0, 1); day.Before(end); day = day.AddDate(0, 0, 1) {
+		bounds = append(bounds, day)
+	}
+	if len(bounds) == 0 {
+		return []*ics.VEvent{e}
+	}
+	bounds = append([]time.Time{start}, append(bounds, end)...)
+
+	uid := e.Id()
+	pieces := make([]*ics.VEvent, len(bounds)-1)
+	for i := range pieces {
+		piece := cloneEvent(e)
+		if i > 0 {
+			piece.SetProperty(ics.ComponentPropertyUniqueId, fmt.Sprintf("%s-%d", uid, i+1))
+			piece.AddProperty(ics.ComponentProperty(ics.PropertyRelatedTo), uid)
+		}
+		setSpan(piece, bounds[i], bounds[i+1])
+		pieces[i] = piece
+	}
+	return pieces
+}
+
+// coalesceEvents merges the events in group that overlap, or are at most the duration in the
+// modifier data apart, into the first of them. The descriptions of merged events are joined.
+// Recurring events are left alone, stretching them would change every occurrence.
+func coalesceEvents(events, group []*ics.VEvent, m *config.Modifier) []*ics.VEvent {
+	var gap time.Duration
+	if m.Data != "" {
+		d, err := config.ParseDuration(m.Data)
+		if err != nil {
+			log.Logger.Warn("Failed to apply modifier", "modifier_name", m.Name, "error", err)
+			return events
+		}
+		gap = d
+	}
+
+	spans := make([]spanEvent, 0, len(group))
+	for _, e := range group {
+		if e.GetProperty(ics.ComponentPropertyRrule) != nil {
+			log.Logger.Debug("Not coalescing recurring event", "modifier_name", m.Name, "event_id", e.Id())
+			continue
+		}
+		if se, ok := newSpanEvent("", e); ok {
+			spans = append(spans, se)
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].Start.Before(spans[j].Start)
+	})
+
+	removed := map[*ics.VEvent]bool{}
+	for i := 0; i < len(spans); {
+		first, end := spans[i], spans[i].End
+		chain := []*ics.VEvent{first.event}
+
+		j := i + 1
+		for ; j < len(spans) && !spans[j].Start.After(end.Add(gap)); j++ {
+			chain = append(chain, spans[j].event)
+			removed[spans[j].event] = true
+			end = latest(end, spans[j].End)
+		}
+		i = j
+
+		if len(chain) == 1 {
+			continue
+		}
+		setSpan(first.event, first.Start, end)
+		mergeDescriptions(first.event, chain)
+	}
+
+	return slices.DeleteFunc(events, func(e *ics.VEvent) bool {
+		return removed[e]
+	})
+}
+
+// mergeDescriptions sets the description of e to the distinct descriptions of the events
+func mergeDescriptions(e *ics.VEvent, events []*ics.VEvent) {
+	var descriptions []string
+	for _, other := range events {
+		p := other.GetProperty(ics.ComponentPropertyDescription)
+		if p == nil || p.Value == "" || slices.Contains(descriptions, p.Value) {
+			continue
+		}
+		descriptions = append(descriptions, p.Value)
+	}
+	// the values are still escaped, so they are joined by an escaped newline
+	if len(descriptions) > 0 {
+		e.SetProperty(ics.ComponentPropertyDescription, strings.Join(descriptions, `\n`))
+	}
+}
@@ -0,0 +1,95 @@
+package ical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	start := time.Date(2024, 3, 4, 22, 0, 0, 0, time.Local)
+
+	cal := newLoadedCal("school",
+		newEventWithSpan("night", start, 28*time.Hour),
+		newEventWithSpan("short", start, time.Hour),
+	)
+	cal.source.Modifiers = []config.Modifier{{Action: config.SPLIT}}
+	cal.Filter()
+
+	events := cal.Events()
+	assert.Len(t, events, 4)
+	for i, want := range []struct {
+		uid        string
+		start, end time.Time
+	}{
+		{"night", start, start.Add(2 * time.Hour)},
+		{"night-2", start.Add(2 * time.Hour), start.Add(26 * time.Hour)},
+		{"night-3", start.Add(26 * time.Hour), start.Add(28 * time.Hour)},
+		{"short", start, start.Add(time.Hour)},
+	} {
+		s, e, err := eventSpan(events[i])
+		assert.NoError(t, err)
+		assert.Equal(t, want.uid, events[i].Id())
+		assert.True(t, want.start.Equal(s), want.uid)
+		assert.True(t, want.end.Equal(e), want.uid)
+	}
+	// the first piece keeps the UID, the others refer to it
+	assert.Nil(t, events[0].GetProperty(ics.ComponentProperty(ics.PropertyRelatedTo)))
+	assert.Equal(t, "night", events[1].GetProperty(ics.ComponentProperty(ics.PropertyRelatedTo)).Value)
+	assert.Equal(t, "night", events[2].GetProperty(ics.ComponentProperty(ics.PropertyRelatedTo)).Value)
+}
+
+func TestCoalesce(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	shift := func(id string, hour float64, description string) *ics.VEvent {
+		e := newEventWithSpan(id, day.Add(time.Duration(hour*float64(time.Hour))), time.Hour)
+		e.SetSummary("Shift")
+		e.SetDescription(description)
+		return e
+	}
+	meeting := newEventWithSpan("meeting", day.Add(10*time.Hour), time.Hour)
+	meeting.SetSummary("Meeting")
+	// a recurring shift is not stretched over the others
+	weekly := shift("weekly", 8.5, "Briefing")
+	weekly.AddRrule("FREQ=WEEKLY")
+
+	cal := newLoadedCal("work",
+		shift("s2", 10, "Desk"),
+		shift("s1", 9, "Desk"),
+		meeting,
+		shift("s3", 11.25, "Phones"),
+		shift("s4", 14, "Desk"),
+		weekly,
+	)
+	cal.source.Modifiers = []config.Modifier{{
+		Action:  config.COALESCE,
+		Data:    "PT15M",
+		Filters: []config.Rule{{Component: "SUMMARY", Check: FilterEqualsTerm, Data: []string{"Shift"}}},
+	}}
+	cal.Filter()
+
+	events := cal.Events()
+	assert.Len(t, events, 4)
+	assert.Equal(t, "meeting", events[1].Id())
+
+	merged := events[0]
+	assert.Equal(t, "s1", merged.Id())
+	s, e, err := eventSpan(merged)
+	assert.NoError(t, err)
+	assert.Equal(t, day.Add(9*time.Hour), s)
+	assert.Equal(t, day.Add(12*time.Hour+15*time.Minute), e)
+	assert.Equal(t, `Desk\nPhones`, merged.GetProperty(ics.ComponentPropertyDescription).Value)
+
+	assert.Equal(t, "s4", events[2].Id())
+	assert.Equal(t, "weekly", events[3].Id())
+	s, e, err = eventSpan(events[3])
+	assert.NoError(t, err)
+	assert.Equal(t, day.Add(9*time.Hour+30*time.Minute), e)
+}
@@ -0,0 +1,220 @@
+package ical
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+)
+
+const (
+	defaultTravelSummary = "Travel"
+	defaultPrepSummary   = "Prep"
+)
+
+func init() {
+	RegisterAction(config.TRAVEL, deferStructural, config.RequireModifierPositiveDuration)
+	RegisterAction(config.PREP, deferStructural, config.RequireModifierPositiveDuration)
+	structuralActions[config.TRAVEL] = addTravelEvents
+	structuralActions[config.PREP] = addPrepEvents
+}
+
+// addTravelEvents adds a travel block before the timed events in group with a location, unless
+// the last event with a location before them on the same day takes place at the same location
+func addTravelEvents(events, group []*ics.VEvent, m *config.Modifier) []*ics.VEvent {
+	d, err := config.ParseDuration(m.Data)
+	if err != nil {
+		log.Logger.Warn("Failed to apply modifier", "modifier_name", m.Name, "error", err)
+		return events
+	}
+
+	for _, e := range group {
+		if isAllDay(e) {
+			continue
+		}
+		location := eventLocation(e)
+		if location == "" {
+			continue
+		}
+		start, _, err := eventSpan(e)
+		if err != nil {
+			continue
+		}
+
+		previous := previousEvent(events, e, start)
+		if previous != nil && strings.EqualFold(eventLocation(previous), location) {
+			continue
+		}
+
+		travel, err := syntheticEvent(events, e, m, "travel", defaultTravelSummary, start.Add(-d), start)
+		if err != nil {
+			log.Logger.Warn("Failed to apply modifier", "modifier_name", m.Name, "event_id", e.Id(), "error", err)
+			continue
+		}
+		events = insertBefore(events, e, travel)
+	}
+	return events
+}
+
+// addPrepEvents adds a preparation block before the events in group, ending the before
+// duration of the modifier before the event starts
+func addPrepEvents(events, group []*ics.VEvent, m *config.Modifier) []*ics.VEvent {
+	d, err := config.ParseDuration(m.Data)
+	if err != nil {
+		log.Logger.Warn("Failed to apply modifier", "modifier_name", m.Name, "error", err)
+		return events
+	}
+	var before time.Duration
+	if m.Before != "" {
+		if before, err = config.ParseDuration(m.Before); err != nil {
+			log.Logger.Warn("Failed to apply modifier", "modifier_name", m.Name, "error", err)
+			return events
+		}
+	}
+
+	for _, e := range group {
+		start, _, err := eventSpan(e)
+		if err != nil {
+			continue
+		}
+		end := start.Add(-before)
+		prep, err := syntheticEvent(events, e, m, "prep", defaultPrepSummary, end.Add(-d), end)
+		if err != nil {
+			log.Logger.Warn("Failed to apply modifier", "modifier_name", m.Name, "event_id", e.Id(), "error", err)
+			continue
+		}
+		events = insertBefore(events, e, prep)
+	}
+	return events
+}
+
+// previousEvent returns the timed event with a location ending last before start on the same day as e
+func previousEvent(events []*ics.VEvent, e *ics.VEvent, start time.Time) *ics.VEvent {
+	day := wallClock(start).Format(time.DateOnly)
+
+	var (
+		previous *ics.VEvent
+		prevEnd  time.Time
+	)
+	for _, other := range events {
+		if other == e || isAllDay(other) || eventLocation(other) == "" {
+			continue
+		}
+		s, end, err := eventSpan(other)
+		if err != nil || end.After(start) || wallClock(s).Format(time.DateOnly) != day {
+			continue
+		}
+		if previous == nil || end.After(prevEnd) {
+			previous, prevEnd = other, end
+		}
+	}
+	return previous
+}
+
+func eventLocation(e *ics.VEvent) string {
+	if p := e.GetProperty(ics.ComponentPropertyLocation); p != nil {
+		return strings.TrimSpace(p.Value)
+	}
+	return ""
+}
+
+// syntheticSummary renders the summary template of the modifier for parent
+func syntheticSummary(parent *ics.VEvent, m *config.Modifier, fallback string) (string, error) {
+	if m.Summary == "" {
+		return fallback, nil
+	}
+	tmpl, err := parseTemplate(m.Summary)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, newEventData(parent, nil)); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// syntheticEvent returns a busy event from start to end, written in the same time format as
+// parent. Its UID is derived from the UID of parent, and the RECURRENCE-ID of an overridden
+// occurrence, so it is stable across refreshes. Blocks of a recurring event recur with it.
+func syntheticEvent(events []*ics.VEvent, parent *ics.VEvent, m *config.Modifier, kind, fallback string, start, end time.Time) (*ics.VEvent, error) {
+	summary, err := syntheticSummary(parent, m, fallback)
+	if err != nil {
+		return nil, err
+	}
+
+	uid := parent.Id() + "-" + kind
+	if p := parent.GetProperty(ics.ComponentProperty(ics.PropertyRecurrenceId)); p != nil {
+		uid += "-" + p.Value
+	}
+	e := ics.NewEvent(uid)
+	if p := parent.GetProperty(ics.ComponentPropertyDtstamp); p != nil {
+		e.SetProperty(ics.ComponentPropertyDtstamp, p.Value)
+	} else {
+		e.SetDtStampTime(start)
+	}
+	e.SetSummary(ics.ToText(summary))
+	e.SetTimeTransparency(ics.TransparencyOpaque)
+	e.AddProperty(ics.ComponentProperty(ics.PropertyRelatedTo), parent.Id())
+
+	f := timeFormatOf(parent.GetProperty(ics.ComponentPropertyDtStart))
+	f.date = false
+	f.set(e, ics.ComponentPropertyDtStart, start)
+	f.set(e, ics.ComponentPropertyDtEnd, end)
+
+	// the offset is taken in the format of parent, the one its excluded occurrences are read in
+	if p := parent.GetProperty(ics.ComponentPropertyDtStart); p != nil {
+		if parentStart, err := timeFormatOf(p).parse(p.Value); err == nil {
+			recurWith(events, parent, e, f, start.Sub(parentStart))
+		}
+	}
+	return e, nil
+}
+
+// recurWith copies the RRULE of parent to the synthetic event, and excludes the occurrences
+// excluded from parent or overridden by another event, moved by the offset of the synthetic event
+func recurWith(events []*ics.VEvent, parent, e *ics.VEvent, f timeFormat, offset time.Duration) {
+	if parent.GetProperty(ics.ComponentPropertyRrule) == nil {
+		return
+	}
+
+	var excluded []*ics.IANAProperty
+	for i, p := range parent.Properties {
+		switch ics.ComponentProperty(p.IANAToken) {
+		case ics.ComponentPropertyRrule:
+			e.AddRrule(p.Value)
+		case ics.ComponentPropertyExdate:
+			excluded = append(excluded, &parent.Properties[i])
+		}
+	}
+	for _, other := range events {
+		if other != parent && other.Id() == parent.Id() {
+			if p := other.GetProperty(ics.ComponentProperty(ics.PropertyRecurrenceId)); p != nil {
+				excluded = append(excluded, p)
+			}
+		}
+	}
+	for _, p := range excluded {
+		pf := timeFormatOf(p)
+		for _, value := range strings.Split(p.Value, ",") {
+			t, err := pf.parse(value)
+			if err != nil {
+				log.Logger.Debug("Ignoring invalid excluded occurrence", "event_id", parent.Id(), "value", value, "error", err)
+				continue
+			}
+			e.AddExdate(f.format(t.Add(offset)), f.params()...)
+		}
+	}
+}
+
+// insertBefore inserts the event before the position of e, or appends it when e is missing
+func insertBefore(events []*ics.VEvent, e *ics.VEvent, inserted *ics.VEvent) []*ics.VEvent {
+	i := slices.Index(events, e)
+	if i < 0 {
+		return append(events, inserted)
+	}
+	return slices.Insert(events, i, inserted)
+}
@@ -0,0 +1,117 @@
+package ical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEventAt(id, location string, start time.Time, d time.Duration) *ics.VEvent {
+	e := newEventWithSpan(id, start, d)
+	if location != "" {
+		e.SetLocation(location)
+	}
+	return e
+}
+
+func TestTravel(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)
+
+	cal := newLoadedCal("school",
+		newEventAt("lecture", "Building A", day.Add(9*time.Hour), time.Hour),
+		newEventAt("online", "", day.Add(10*time.Hour), time.Hour),
+		newEventAt("lab", "Building B", day.Add(12*time.Hour), time.Hour),
+		newEventAt("seminar", "building b", day.Add(14*time.Hour), time.Hour),
+		newEventAt("tomorrow", "Building C", day.Add(33*time.Hour), time.Hour),
+	)
+	cal.source.Modifiers = []config.Modifier{{Name: "Commute", Action: config.TRAVEL, Data: "PT20M"}}
+	cal.Filter()
+
+	var ids []string
+	for _, e := range cal.Events() {
+		ids = append(ids, e.Id())
+	}
+	// the first event with a location of a day is travelled to as well
+	assert.Equal(t, []string{"lecture-travel", "lecture", "online", "lab-travel", "lab", "seminar", "tomorrow-travel", "tomorrow"}, ids)
+
+	travel := cal.Events()[3]
+	start, end, err := eventSpan(travel)
+	assert.NoError(t, err)
+	assert.True(t, day.Add(11*time.Hour+40*time.Minute).Equal(start))
+	assert.True(t, day.Add(12*time.Hour).Equal(end))
+	// the name of the modifier is not used as summary
+	assert.Equal(t, "Travel", travel.GetProperty(ics.ComponentPropertySummary).Value)
+	assert.Equal(t, "OPAQUE", travel.GetProperty(ics.ComponentPropertyTransp).Value)
+	assert.Equal(t, "lab", travel.GetProperty(ics.ComponentProperty(ics.PropertyRelatedTo)).Value)
+}
+
+func TestPrep(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	exam := newEventAt("exam", "Hall", day.Add(9*time.Hour), 2*time.Hour)
+	exam.SetSummary("Exam Algebra")
+	cal := newLoadedCal("school", exam, newEventAt("lecture", "Hall", day.Add(13*time.Hour), time.Hour))
+	cal.source.Modifiers = []config.Modifier{{
+		Name:    "Study",
+		Summary: "Study for {{.Summary}}",
+		Action:  config.PREP,
+		Data:    "PT2H",
+		Before:  "P1D",
+		Filters: []config.Rule{{Component: "SUMMARY", Check: FilterContainsTerm, Data: []string{"exam"}}},
+	}}
+	cal.Filter()
+
+	events := cal.Events()
+	assert.Len(t, events, 3)
+	prep := events[0]
+	assert.Equal(t, "exam-prep", prep.Id())
+	assert.Equal(t, "Study for Exam Algebra", prep.GetProperty(ics.ComponentPropertySummary).Value)
+	start, end, err := eventSpan(prep)
+	assert.NoError(t, err)
+	assert.Equal(t, day.Add(-24*time.Hour+7*time.Hour), start)
+	assert.Equal(t, day.Add(-24*time.Hour+9*time.Hour), end)
+}
+
+func TestTravelRecurring(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	lecture := newEventAt("lecture", "Building A", day.Add(9*time.Hour), time.Hour)
+	lecture.AddRrule("FREQ=WEEKLY;COUNT=4")
+	lecture.AddExdate("20240318T090000Z")
+	// the second lecture moves to the afternoon
+	moved := newEventAt("lecture", "Building A", day.Add(7*24*time.Hour+14*time.Hour), time.Hour)
+	moved.SetProperty(ics.ComponentProperty(ics.PropertyRecurrenceId), "20240311T090000Z")
+
+	cal := newLoadedCal("school", lecture, moved)
+	cal.source.Modifiers = []config.Modifier{{Action: config.TRAVEL, Data: "PT30M"}}
+	cal.Filter()
+
+	events := cal.Events()
+	if !assert.Len(t, events, 4) {
+		return
+	}
+	series, override := events[0], events[2]
+	assert.Equal(t, "lecture-travel", series.Id())
+	assert.Equal(t, "FREQ=WEEKLY;COUNT=4", series.GetProperty(ics.ComponentPropertyRrule).Value)
+	var exdates []string
+	for _, p := range series.Properties {
+		if p.IANAToken == string(ics.ComponentPropertyExdate) {
+			exdates = append(exdates, p.Value)
+		}
+	}
+	// the excluded and the overridden occurrence, moved to the start of their travel blocks
+	assert.Equal(t, []string{"20240318T083000Z", "20240311T083000Z"}, exdates)
+
+	assert.Equal(t, "lecture-travel-20240311T090000Z", override.Id())
+	assert.Nil(t, override.GetProperty(ics.ComponentPropertyRrule))
+	start, _, err := eventSpan(override)
+	assert.NoError(t, err)
+	assert.Equal(t, day.Add(7*24*time.Hour+13*time.Hour+30*time.Minute), start)
+}
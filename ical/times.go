@@ -0,0 +1,200 @@
+package ical
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+)
+
+// now returns the current time, tests replace it to get stable results
+var now = time.Now
+
+// isAllDay reports whether the event start is a date rather than a date-time
+func isAllDay(e *ics.VEvent) bool {
+	p := e.GetProperty(ics.ComponentPropertyDtStart)
+	if p == nil {
+		return false
+	}
+	if v, ok := p.ICalParameters[string(ics.ParameterValue)]; ok && len(v) == 1 && v[0] == string(ics.ValueDataTypeDate) {
+		return true
+	}
+	return len(p.Value) == 8
+}
+
+// eventSpan returns the start and end of the event. The end is taken from DTEND or DURATION,
+// when neither is present all-day events last one day and other events are instantaneous.
+func eventSpan(e *ics.VEvent) (time.Time, time.Time, error) {
+	start, err := e.GetStartAt()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if end, err := e.GetEndAt(); err == nil {
+		if end.Before(start) {
+			return time.Time{}, time.Time{}, errors.New("event ends before it starts")
+		}
+		return start, end, nil
+	}
+
+	if p := e.GetProperty(ics.ComponentProperty(ics.PropertyDuration)); p != nil {
+		d, err := config.ParseDuration(p.Value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if isAllDay(e) && d%(24*time.Hour) == 0 {
+			return start, start.AddDate(0, 0, int(d/(24*time.Hour))), nil
+		}
+		return start, start.Add(d), nil
+	}
+
+	if isAllDay(e) {
+		return start, start.AddDate(0, 0, 1), nil
+	}
+	return start, start, nil
+}
+
+// overlaps reports whether the half open intervals [s1, e1) and [s2, e2) overlap,
+// instantaneous events overlap intervals containing them
+func overlaps(s1, e1, s2, e2 time.Time) bool {
+	if s1.Equal(e1) {
+		return !s1.Before(s2) && s1.Before(e2)
+	}
+	if s2.Equal(e2) {
+		return !s2.Before(s1) && s2.Before(e1)
+	}
+	return s1.Before(e2) && s2.Before(e1)
+}
+
+// timeFormat remembers how a date-time property was written so it can be written back the same way
+type timeFormat struct {
+	date bool
+	utc  bool
+	tzid string
+	loc  *time.Location
+}
+
+// timeFormatOf returns the format of the property, falling back to UTC
+func timeFormatOf(p *ics.IANAProperty) timeFormat {
+	if p == nil {
+		return timeFormat{utc: true}
+	}
+
+	f := timeFormat{loc: time.Local}
+	if v, ok := p.ICalParameters[string(ics.ParameterValue)]; ok && len(v) == 1 && v[0] == string(ics.ValueDataTypeDate) {
+		f.date = true
+	} else if len(p.Value) == 8 {
+		f.date = true
+	}
+
+	if tzid, ok := p.ICalParameters[string(ics.ParameterTzid)]; ok && len(tzid) == 1 {
+		if loc, err := time.LoadLocation(tzid[0]); err == nil {
+			f.tzid, f.loc = tzid[0], loc
+		}
+	} else if strings.HasSuffix(p.Value, "Z") {
+		f.utc, f.loc = true, time.UTC
+	}
+	return f
+}
+
+// set writes t to the property in the remembered format
+func (f timeFormat) set(e *ics.VEvent, prop ics.ComponentProperty, t time.Time) {
+	e.SetProperty(prop, f.format(t), f.params()...)
+}
+
+// format returns t written in the remembered format
+func (f timeFormat) format(t time.Time) string {
+	switch {
+	case f.date:
+		return t.In(f.loc).Format("20060102")
+	case f.utc:
+		return t.UTC().Format("20060102T150405Z")
+	default:
+		return t.In(f.loc).Format("20060102T150405")
+	}
+}
+
+// parse reads a value written in the format, values ending in Z are always UTC
+func (f timeFormat) parse(value string) (time.Time, error) {
+	loc := f.loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	case len(value) == 8:
+		return time.ParseInLocation("20060102", value, loc)
+	default:
+		return time.ParseInLocation("20060102T150405", value, loc)
+	}
+}
+
+// params returns the TZID and VALUE parameters of the format
+func (f timeFormat) params() []ics.PropertyParameter {
+	var params []ics.PropertyParameter
+	if f.tzid != "" {
+		params = append(params, &ics.KeyValues{Key: string(ics.ParameterTzid), Value: []string{f.tzid}})
+	}
+	if f.date {
+		params = append(params, ics.WithValue(string(ics.ValueDataTypeDate)))
+	}
+	return params
+}
+
+// setSpan moves the event to start and end, updating DTEND or DURATION depending on
+// which of the two the event uses
+func setSpan(e *ics.VEvent, start, end time.Time) {
+	startProp := e.GetProperty(ics.ComponentPropertyDtStart)
+	f := timeFormatOf(startProp)
+	f.set(e, ics.ComponentPropertyDtStart, start)
+
+	durationProp := ics.ComponentProperty(ics.PropertyDuration)
+	switch {
+	case e.GetProperty(durationProp) != nil && e.GetProperty(ics.ComponentPropertyDtEnd) == nil:
+		e.SetProperty(durationProp, formatDuration(end.Sub(start)))
+	case e.GetProperty(ics.ComponentPropertyDtEnd) != nil:
+		timeFormatOf(e.GetProperty(ics.ComponentPropertyDtEnd)).set(e, ics.ComponentPropertyDtEnd, end)
+	case !end.Equal(start):
+		f.set(e, ics.ComponentPropertyDtEnd, end)
+	}
+}
+
+// formatDuration formats d as an RFC 5545 duration
+func formatDuration(d time.Duration) string {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteString("-")
+		d = -d
+	}
+	b.WriteString("P")
+
+	day := 24 * time.Hour
+	if d >= day && d%day == 0 {
+		if d%(7*day) == 0 {
+			return b.String() + strconv.Itoa(int(d/(7*day))) + "W"
+		}
+		return b.String() + strconv.Itoa(int(d/day)) + "D"
+	}
+	if d >= day {
+		b.WriteString(strconv.Itoa(int(d/day)) + "D")
+		d %= day
+	}
+
+	b.WriteString("T")
+	if d >= time.Hour {
+		b.WriteString(strconv.Itoa(int(d/time.Hour)) + "H")
+		d %= time.Hour
+	}
+	if d >= time.Minute {
+		b.WriteString(strconv.Itoa(int(d/time.Minute)) + "M")
+		d %= time.Minute
+	}
+	if d > 0 || strings.HasSuffix(b.String(), "T") {
+		b.WriteString(strconv.Itoa(int(d/time.Second)) + "S")
+	}
+	return b.String()
+}
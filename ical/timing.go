@@ -0,0 +1,187 @@
+package ical
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+)
+
+func init() {
+	RegisterAction(config.SHIFT, actionShift, config.RequireModifierDuration)
+	RegisterAction(config.DURATION, actionDuration, config.RequireModifierDuration)
+	RegisterAction(config.SNAP, actionSnap, config.RequireModifierPositiveDuration)
+	RegisterAction(config.TO_ALL_DAY, actionToAllDay)
+	RegisterAction(config.TO_TIMED, actionToTimed, config.RequireModifierTimeRange)
+	RegisterAction(config.CLAMP, actionClamp, config.RequireModifierTimeRange)
+}
+
+// actionShift moves the event by the duration in the modifier data
+func actionShift(e *ics.VEvent, ctx ModifierContext) error {
+	d, err := config.ParseDuration(ctx.Modifier.Data)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := eventSpan(e)
+	if err != nil {
+		return err
+	}
+	allDay := isAllDay(e)
+	setSpan(e, shiftTime(start, d, allDay), shiftTime(end, d, allDay))
+	return nil
+}
+
+// shiftTime moves t by d. Dates move by calendar days, so a day stays a day when the clocks change.
+func shiftTime(t time.Time, d time.Duration, date bool) time.Time {
+	if !date {
+		return t.Add(d)
+	}
+	day := 24 * time.Hour
+	return t.AddDate(0, 0, int(d/day)).Add(d % day)
+}
+
+// actionDuration sets the length of the event to the duration in the modifier data
+func actionDuration(e *ics.VEvent, ctx ModifierContext) error {
+	d, err := config.ParseDuration(ctx.Modifier.Data)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return errors.New("duration must not be negative")
+	}
+
+	start, _, err := eventSpan(e)
+	if err != nil {
+		return err
+	}
+	setSpan(e, start, start.Add(d))
+	return nil
+}
+
+// actionSnap widens the event so it starts and ends on the grid of the duration in the modifier data.
+// The grid is aligned to midnight in the time zone of the event.
+func actionSnap(e *ics.VEvent, ctx ModifierContext) error {
+	grid, err := config.ParseDuration(ctx.Modifier.Data)
+	if err != nil {
+		return err
+	}
+	if grid <= 0 {
+		return errors.New("grid must be positive")
+	}
+	if isAllDay(e) {
+		return nil
+	}
+
+	start, end, err := eventSpan(e)
+	if err != nil {
+		return err
+	}
+	start, end = wallClock(start), wallClock(end)
+
+	snappedStart := midnight(start).Add(start.Sub(midnight(start)) / grid * grid)
+	snappedEnd := midnight(end).Add(end.Sub(midnight(end)) / grid * grid)
+	if snappedEnd.Before(end) {
+		snappedEnd = snappedEnd.Add(grid)
+	}
+	setSpan(e, snappedStart, snappedEnd)
+	return nil
+}
+
+// actionToAllDay turns a timed event into an all-day event covering the same days
+func actionToAllDay(e *ics.VEvent, ctx ModifierContext) error {
+	if isAllDay(e) {
+		return nil
+	}
+
+	start, end, err := eventSpan(e)
+	if err != nil {
+		return err
+	}
+
+	// the days are those on the clock of the event, not of the server
+	start, end = wallClock(start), wallClock(end)
+	if end.After(start) {
+		end = end.Add(-time.Nanosecond)
+	}
+
+	removeProperty(&e.ComponentBase, ics.ComponentProperty(ics.PropertyDuration))
+	e.SetAllDayStartAt(midnight(start))
+	e.SetAllDayEndAt(midnight(end).AddDate(0, 0, 1))
+	return nil
+}
+
+// actionToTimed turns an all-day event into a timed event, starting at the start of the time
+// range in the modifier data on the first day and ending at its end on the last day
+func actionToTimed(e *ics.VEvent, ctx ModifierContext) error {
+	if !isAllDay(e) {
+		return nil
+	}
+
+	hours, err := config.ParseTimeRange(ctx.Modifier.Data)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := eventSpan(e)
+	if err != nil {
+		return err
+	}
+
+	lastDay := end.AddDate(0, 0, -1)
+	if lastDay.Before(start) {
+		lastDay = start
+	}
+
+	removeProperty(&e.ComponentBase, ics.ComponentProperty(ics.PropertyDuration))
+	floating := timeFormat{loc: time.Local}
+	floating.set(e, ics.ComponentPropertyDtStart, atClock(start, hours.Start))
+	floating.set(e, ics.ComponentPropertyDtEnd, atClock(lastDay, hours.End))
+	return nil
+}
+
+// actionClamp limits the event to the time range in the modifier data on the day it starts.
+// Events entirely outside the range are left untouched.
+func actionClamp(e *ics.VEvent, ctx ModifierContext) error {
+	if isAllDay(e) {
+		return nil
+	}
+
+	hours, err := config.ParseTimeRange(ctx.Modifier.Data)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := eventSpan(e)
+	if err != nil {
+		return err
+	}
+	start, end = wallClock(start), wallClock(end)
+
+	from, to := atClock(start, hours.Start), atClock(start, hours.End)
+	if !overlaps(start, end, from, to) {
+		return nil
+	}
+	setSpan(e, latest(start, from), earliest(end, to))
+	return nil
+}
+
+// midnight returns the start of the day of t in its own location
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// atClock returns the wall clock time d after midnight on the day of t
+func atClock(t time.Time, d time.Duration) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, int(d/time.Minute), 0, 0, t.Location())
+}
+
+// wallClock moves UTC times to the local time zone, so day based logic uses local days.
+// Times with a TZID are kept in their own zone.
+func wallClock(t time.Time) time.Time {
+	if t.Location() == time.UTC {
+		return t.In(time.Local)
+	}
+	return t
+}
@@ -0,0 +1,129 @@
+package ical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func newZonedEvent(start, end string) *ics.VEvent {
+	tzid := &ics.KeyValues{Key: "TZID", Value: []string{"Europe/Amsterdam"}}
+	e := ics.NewEvent("1")
+	e.SetProperty(ics.ComponentPropertyDtStart, start, tzid)
+	if end != "" {
+		e.SetProperty(ics.ComponentPropertyDtEnd, end, tzid)
+	}
+	return e
+}
+
+func prop(e *ics.VEvent, p ics.ComponentProperty) string {
+	if v := e.GetProperty(p); v != nil {
+		return v.Value
+	}
+	return ""
+}
+
+func TestActionShift(t *testing.T) {
+	e := newZonedEvent("20240304T090000", "20240304T100000")
+	applyAction(t, e, config.Modifier{Action: config.SHIFT, Data: "-PT15M"})
+	assert.Equal(t, "20240304T084500", prop(e, ics.ComponentPropertyDtStart))
+	assert.Equal(t, "20240304T094500", prop(e, ics.ComponentPropertyDtEnd))
+	assert.Equal(t, []string{"Europe/Amsterdam"}, e.GetProperty(ics.ComponentPropertyDtStart).ICalParameters["TZID"])
+
+	// events using DURATION keep it
+	e = ics.NewEvent("2")
+	e.SetProperty(ics.ComponentPropertyDtStart, "20240304T090000Z")
+	e.SetProperty(ics.ComponentProperty(ics.PropertyDuration), "PT1H")
+	applyAction(t, e, config.Modifier{Action: config.SHIFT, Data: "P1D"})
+	assert.Equal(t, "20240305T090000Z", prop(e, ics.ComponentPropertyDtStart))
+	assert.Equal(t, "PT1H", prop(e, ics.ComponentProperty(ics.PropertyDuration)))
+	assert.Nil(t, e.GetProperty(ics.ComponentPropertyDtEnd))
+
+	// all-day events stay dates
+	e = ics.NewEvent("3")
+	e.SetAllDayStartAt(time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local))
+	e.SetAllDayEndAt(time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local))
+	applyAction(t, e, config.Modifier{Action: config.SHIFT, Data: "P1W"})
+	assert.Equal(t, "20240311", prop(e, ics.ComponentPropertyDtStart))
+	assert.Equal(t, "20240312", prop(e, ics.ComponentPropertyDtEnd))
+	assert.Equal(t, []string{"DATE"}, e.GetProperty(ics.ComponentPropertyDtStart).ICalParameters["VALUE"])
+}
+
+func TestActionShiftAllDayAcrossDST(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skip("time zone data is not available")
+	}
+	defer func(loc *time.Location) { time.Local = loc }(time.Local)
+	time.Local = amsterdam
+
+	// the clocks go back on 2024-10-27, making it 25 hours long
+	for _, tc := range []struct {
+		data, start, end string
+	}{
+		{"P1D", "20241028", "20241029"},
+		{"-P1D", "20241026", "20241027"},
+		{"P1W", "20241103", "20241104"},
+	} {
+		e := ics.NewEvent("1")
+		e.SetAllDayStartAt(time.Date(2024, 10, 27, 0, 0, 0, 0, time.Local))
+		e.SetAllDayEndAt(time.Date(2024, 10, 28, 0, 0, 0, 0, time.Local))
+		applyAction(t, e, config.Modifier{Action: config.SHIFT, Data: tc.data})
+		assert.Equal(t, tc.start, prop(e, ics.ComponentPropertyDtStart), tc.data)
+		assert.Equal(t, tc.end, prop(e, ics.ComponentPropertyDtEnd), tc.data)
+	}
+}
+
+func TestActionDuration(t *testing.T) {
+	e := newZonedEvent("20240304T090000", "20240304T100000")
+	applyAction(t, e, config.Modifier{Action: config.DURATION, Data: "PT90M"})
+	assert.Equal(t, "20240304T103000", prop(e, ics.ComponentPropertyDtEnd))
+
+	e = newZonedEvent("20240304T090000", "")
+	applyAction(t, e, config.Modifier{Action: config.DURATION, Data: "PT30M"})
+	assert.Equal(t, "20240304T093000", prop(e, ics.ComponentPropertyDtEnd))
+	assert.Equal(t, []string{"Europe/Amsterdam"}, e.GetProperty(ics.ComponentPropertyDtEnd).ICalParameters["TZID"])
+}
+
+func TestActionSnap(t *testing.T) {
+	e := newZonedEvent("20240304T090700", "20240304T095000")
+	applyAction(t, e, config.Modifier{Action: config.SNAP, Data: "PT15M"})
+	assert.Equal(t, "20240304T090000", prop(e, ics.ComponentPropertyDtStart))
+	assert.Equal(t, "20240304T100000", prop(e, ics.ComponentPropertyDtEnd))
+}
+
+func TestActionAllDay(t *testing.T) {
+	e := newZonedEvent("20240304T090000", "20240305T100000")
+	applyAction(t, e, config.Modifier{Action: config.TO_ALL_DAY})
+	assert.Equal(t, "20240304", prop(e, ics.ComponentPropertyDtStart))
+	assert.Equal(t, "20240306", prop(e, ics.ComponentPropertyDtEnd))
+	assert.True(t, isAllDay(e))
+
+	applyAction(t, e, config.Modifier{Action: config.TO_TIMED, Data: "09:00-17:00"})
+	assert.Equal(t, "20240304T090000", prop(e, ics.ComponentPropertyDtStart))
+	assert.Equal(t, "20240305T170000", prop(e, ics.ComponentPropertyDtEnd))
+	assert.False(t, isAllDay(e))
+}
+
+func TestActionClamp(t *testing.T) {
+	e := newZonedEvent("20240304T070000", "20240304T200000")
+	applyAction(t, e, config.Modifier{Action: config.CLAMP, Data: "09:00-17:00"})
+	assert.Equal(t, "20240304T090000", prop(e, ics.ComponentPropertyDtStart))
+	assert.Equal(t, "20240304T170000", prop(e, ics.ComponentPropertyDtEnd))
+
+	e = newZonedEvent("20240304T190000", "20240304T200000")
+	applyAction(t, e, config.Modifier{Action: config.CLAMP, Data: "09:00-17:00"})
+	assert.Equal(t, "20240304T190000", prop(e, ics.ComponentPropertyDtStart))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "PT15M", formatDuration(15*time.Minute))
+	assert.Equal(t, "-PT1H30M", formatDuration(-90*time.Minute))
+	assert.Equal(t, "P2D", formatDuration(48*time.Hour))
+	assert.Equal(t, "P1W", formatDuration(7*24*time.Hour))
+	assert.Equal(t, "P1DT1H", formatDuration(25*time.Hour))
+	assert.Equal(t, "PT0S", formatDuration(0))
+}
@@ -0,0 +1,280 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+)
+
+const (
+	defaultBirthdaySummary    = "{{.Name}}'s birthday"
+	defaultAnniversarySummary = "{{.Name}}'s anniversary"
+)
+
+func init() {
+	RegisterSource(config.SourceVCard, loadVCards, config.RequireVCard)
+}
+
+// vCard holds the fields of a contact used to generate events
+type vCard struct {
+	uid         string
+	name        string
+	birthday    string
+	anniversary string
+}
+
+// vCardData is passed to the summary templates. Age is empty and Year 0 when the year is
+// unknown, and on occurrences without a summary of their own.
+type vCardData struct {
+	Name string
+	Age  string
+	Year int
+}
+
+// loadVCards returns a yearly all-day event for the birthday and anniversary of every contact
+func loadVCards(source config.SourceInfo) ([]*ics.VEvent, error) {
+	birthday, err := summaryTemplate(source.VCard.Summary, defaultBirthdaySummary)
+	if err != nil {
+		return nil, err
+	}
+	anniversary, err := summaryTemplate(source.VCard.AnniversarySummary, defaultAnniversarySummary)
+	if err != nil {
+		return nil, err
+	}
+
+	cards, err := readVCards(source.VCard.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// the age differs per year, occurrences in these years get a summary with the age
+	year := now().Year()
+	years := []int{year - 1, year, year + 1}
+
+	var events []*ics.VEvent
+	for _, card := range cards {
+		for _, kind := range []struct {
+			value   string
+			name    string
+			summary *template.Template
+		}{
+			{card.birthday, "birthday", birthday},
+			{card.anniversary, "anniversary", anniversary},
+		} {
+			if kind.value == "" {
+				continue
+			}
+			yearly, err := yearlyEvents(source, card, kind.name, kind.value, kind.summary, years)
+			if err != nil {
+				log.Logger.Warn("Skipping vCard date", "source", source.Name, "contact", card.name, "error", err)
+				continue
+			}
+			events = append(events, yearly...)
+		}
+	}
+	return events, nil
+}
+
+// yearlyEvents returns a transparent all-day event recurring every year on the date. When the
+// year is known the occurrences in years are overridden, so their summary has the age reached.
+func yearlyEvents(source config.SourceInfo, card vCard, kind, value string, summary *template.Template, years []int) ([]*ics.VEvent, error) {
+	year, month, day, err := parseVCardDate(value)
+	if err != nil {
+		return nil, err
+	}
+
+	id := card.uid
+	if id == "" {
+		id = card.name
+	}
+	uid := generatedUid(source, id, kind)
+
+	render := func(age string) (string, error) {
+		var b strings.Builder
+		err := summary.Execute(&b, vCardData{Name: card.name, Age: age, Year: year})
+		return b.String(), err
+	}
+	newEvent := func(age string, start time.Time) (*ics.VEvent, error) {
+		text, err := render(age)
+		if err != nil {
+			return nil, err
+		}
+		e := newGeneratedEvent(uid, text, start)
+		e.SetAllDayStartAt(start)
+		e.SetAllDayEndAt(start.AddDate(0, 0, 1))
+		e.SetTimeTransparency(ics.TransparencyTransparent)
+		return e, nil
+	}
+
+	// without a year the event starts in a leap year, so February 29 is valid
+	startYear := year
+	if startYear == 0 {
+		startYear = 2000
+	}
+	master, err := newEvent("", time.Date(startYear, month, day, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		return nil, err
+	}
+	if month == time.February && day == 29 {
+		master.AddRrule("FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1")
+	} else {
+		master.AddRrule("FREQ=YEARLY")
+	}
+	events := []*ics.VEvent{master}
+	if year == 0 {
+		return events, nil
+	}
+
+	for _, y := range years {
+		if y < year {
+			continue
+		}
+		start := dateIn(y, month, day)
+		override, err := newEvent(strconv.Itoa(y-year), start)
+		if err != nil {
+			return nil, err
+		}
+		override.SetProperty(ics.ComponentProperty(ics.PropertyRecurrenceId), start.Format("20060102"), ics.WithValue(string(ics.ValueDataTypeDate)))
+		events = append(events, override)
+	}
+	return events, nil
+}
+
+// dateIn returns the month and day in the year. February 29 falls on February 28 in other years.
+func dateIn(year int, month time.Month, day int) time.Time {
+	if month == time.February && day == 29 && time.Date(year, 2, 29, 0, 0, 0, 0, time.Local).Month() != time.February {
+		return time.Date(year, 2, 28, 0, 0, 0, 0, time.Local)
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+// parseVCardDate parses the date forms used by vCard 3 and 4, such as 1985-04-15, 19850415,
+// --0415 and --04-15 when the year is unknown. A time after the date is ignored.
+func parseVCardDate(value string) (int, time.Month, int, error) {
+	value, _, _ = strings.Cut(value, "T")
+	if strings.HasPrefix(value, "--") {
+		t, err := time.Parse("0102", strings.ReplaceAll(value[2:], "-", ""))
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("invalid date %q", value)
+		}
+		return 0, t.Month(), t.Day(), nil
+	}
+
+	t, err := time.Parse("20060102", strings.ReplaceAll(value, "-", ""))
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid date %q", value)
+	}
+	return t.Year(), t.Month(), t.Day(), nil
+}
+
+// readVCards reads the vCards in the file, or in every .vcf file in the directory
+func readVCards(path string) ([]vCard, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return parseVCards(content), nil
+	}
+
+	var cards []vCard
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(p), ".vcf") {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		cards = append(cards, parseVCards(content)...)
+		return nil
+	})
+	return cards, err
+}
+
+// parseVCards parses every vCard in the content, only the fields needed for events are kept
+func parseVCards(content []byte) []vCard {
+	var (
+		cards   []vCard
+		current *vCard
+		lines   []string
+	)
+
+	// unfold lines continued with a leading space or tab
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	for _, line := range lines {
+		head, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		params := strings.Split(head, ";")
+		name := strings.ToUpper(params[0])
+		// properties may be grouped, as in item1.BDAY
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCARD"):
+			current = &vCard{}
+		case name == "END" && strings.EqualFold(value, "VCARD"):
+			if current != nil && current.name != "" {
+				cards = append(cards, *current)
+			}
+			current = nil
+		case current == nil:
+		case name == "UID":
+			current.uid = value
+		case name == "FN":
+			current.name = unescapeVCard(value)
+		case name == "N" && current.name == "":
+			parts := strings.Split(value, ";")
+			if len(parts) > 1 {
+				current.name = strings.TrimSpace(unescapeVCard(parts[1] + " " + parts[0]))
+			}
+		case name == "BDAY" && !textValue(params):
+			current.birthday = value
+		case (name == "ANNIVERSARY" || name == "X-ANNIVERSARY") && !textValue(params):
+			current.anniversary = value
+		}
+	}
+	return cards
+}
+
+// textValue reports whether the parameters mark the value as free text rather than a date
+func textValue(params []string) bool {
+	for _, p := range params[1:] {
+		if strings.EqualFold(p, "VALUE=text") {
+			return true
+		}
+	}
+	return false
+}
+
+func unescapeVCard(s string) string {
+	return strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, "\n", `\N`, "\n", `\\`, `\`).Replace(s)
+}
@@ -0,0 +1,122 @@
+package ical
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Fesaa/ical-merger/config"
+	"github.com/Fesaa/ical-merger/log"
+	ics "github.com/arran4/golang-ical"
+	"github.com/stretchr/testify/assert"
+)
+
+const testVCards = "BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"UID:urn:uuid:alice\r\n" +
+	"FN:Alice\r\n" +
+	"  Smith\r\n" +
+	"BDAY:19850415\r\n" +
+	"ANNIVERSARY:2010-06-20\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:3.0\r\n" +
+	"N:Jones;Bob;;;\r\n" +
+	"item1.BDAY:--02-29\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN:Carol\r\n" +
+	"BDAY;VALUE=text:circa 1800\r\n" +
+	"END:VCARD\r\n"
+
+func TestParseVCards(t *testing.T) {
+	cards := parseVCards([]byte(testVCards))
+	assert.Equal(t, []vCard{
+		{uid: "urn:uuid:alice", name: "Alice Smith", birthday: "19850415", anniversary: "2010-06-20"},
+		{name: "Bob Jones", birthday: "--02-29"},
+		{name: "Carol"},
+	}, cards)
+}
+
+func TestParseVCardDate(t *testing.T) {
+	for value, want := range map[string]string{
+		"19850415":             "1985-04-15",
+		"1985-04-15":           "1985-04-15",
+		"1985-04-15T10:00:00Z": "1985-04-15",
+		"--0415":               "0000-04-15",
+		"--04-15":              "0000-04-15",
+	} {
+		year, month, day, err := parseVCardDate(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Format(time.DateOnly), value)
+	}
+
+	_, _, _, err := parseVCardDate("April")
+	assert.Error(t, err)
+}
+
+func TestLoadVCards(t *testing.T) {
+	log.Init("ERROR", config.Notification{})
+	defer func(fn func() time.Time) { now = fn }(now)
+	now = func() time.Time { return time.Date(2025, 5, 1, 12, 0, 0, 0, time.Local) }
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "contacts.vcf"), []byte(testVCards), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("BEGIN:VCARD\nFN:Nobody\nBDAY:20000101\nEND:VCARD\n"), 0o644))
+
+	source := config.SourceInfo{Name: "Birthdays", Type: config.SourceVCard, VCard: &config.VCard{
+		Path:    dir,
+		Summary: "🎂 {{.Name}} ({{.Age}})",
+	}}
+	events, err := loadVCards(source)
+	assert.NoError(t, err)
+	// a yearly birthday and anniversary for Alice with overrides for 2024, 2025 and 2026, and a yearly birthday for Bob
+	assert.Len(t, events, 9)
+
+	masters := map[string]*ics.VEvent{}
+	overrides := map[string]*ics.VEvent{}
+	for _, e := range events {
+		assert.Equal(t, string(ics.TransparencyTransparent), e.GetProperty(ics.ComponentPropertyTransp).Value)
+		summary := e.GetProperty(ics.ComponentPropertySummary).Value
+		if p := e.GetProperty(ics.ComponentProperty(ics.PropertyRecurrenceId)); p != nil {
+			overrides[summary] = e
+		} else {
+			masters[summary] = e
+		}
+	}
+
+	// the recurring event has no age, as it differs per year
+	alice := masters["🎂 Alice Smith ()"]
+	if assert.NotNil(t, alice) {
+		assert.Equal(t, "19850415", alice.GetProperty(ics.ComponentPropertyDtStart).Value)
+		assert.Equal(t, "19850416", alice.GetProperty(ics.ComponentPropertyDtEnd).Value)
+		assert.Equal(t, "FREQ=YEARLY", alice.GetProperty(ics.ComponentPropertyRrule).Value)
+	}
+	assert.NotNil(t, masters["Alice Smith's anniversary"])
+
+	// the occurrences around now have the age reached that year
+	for year, summary := range map[string]string{"2024": "🎂 Alice Smith (39)", "2025": "🎂 Alice Smith (40)", "2026": "🎂 Alice Smith (41)"} {
+		override := overrides[summary]
+		if assert.NotNil(t, override, summary) && alice != nil {
+			assert.Equal(t, alice.Id(), override.Id())
+			recurrenceId := override.GetProperty(ics.ComponentProperty(ics.PropertyRecurrenceId))
+			assert.Equal(t, year+"0415", recurrenceId.Value)
+			assert.Equal(t, []string{"DATE"}, recurrenceId.ICalParameters["VALUE"])
+			assert.Equal(t, year+"0415", override.GetProperty(ics.ComponentPropertyDtStart).Value)
+			assert.Nil(t, override.GetProperty(ics.ComponentPropertyRrule))
+		}
+	}
+
+	// without a year there is no age to show, February 29 falls on the last day of February
+	bob := masters["🎂 Bob Jones ()"]
+	if assert.NotNil(t, bob) {
+		assert.Equal(t, "20000229", bob.GetProperty(ics.ComponentPropertyDtStart).Value)
+		assert.Equal(t, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1", bob.GetProperty(ics.ComponentPropertyRrule).Value)
+	}
+
+	again, err := loadVCards(source)
+	assert.NoError(t, err)
+	assert.Equal(t, events[0].Id(), again[0].Id())
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"text/template"
 
 	"github.com/Fesaa/ical-merger/config"
@@ -22,6 +25,7 @@ Broadcasting notifications to: {{if .Config.Notification.Service}}{{.Config.Noti
 Publishing:
 {{- range .Config.Sources }}
   {{.Name}}: {{$.Host}}/{{.EndPoint}}.ics
+  {{- if .Conflicts.Enabled }} (conflicts: {{$.Host}}/{{.EndPoint}}.conflicts.json){{end}}
 {{- end }}
 =======================================
 `
@@ -71,8 +75,19 @@ func main() {
 	fmt.Println(motd)
 
 	mux := newServerMux(c)
-
-	e = http.ListenAndServe(host, mux)
+	srv := &http.Server{Addr: host, Handler: mux}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		log.Logger.Info("Shutting down")
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	e = srv.ListenAndServe()
+	// hooks run as separate processes, which would outlive the server
+	ical.StopHooks()
 	if errors.Is(e, http.ErrServerClosed) {
 		log.Logger.Info("Server died", "error", e)
 	} else {
@@ -93,6 +108,9 @@ func newServerMux(c *config.Config) *http.ServeMux {
 		handler := *server.NewServerHandler(ical.FromSource(s))
 		handler.Bootstrap()
 		mux.HandleFunc(fmt.Sprintf("/%s.ics", s.EndPoint), handler.IcsHandler)
+		if s.Conflicts.Enabled {
+			mux.HandleFunc(fmt.Sprintf("/%s.conflicts.json", s.EndPoint), handler.ConflictsHandler)
+		}
 	}
 
 	return mux
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/Fesaa/ical-merger/config"
+	ical2 "github.com/Fesaa/ical-merger/ical"
 	"github.com/Fesaa/ical-merger/log"
 	ical "github.com/arran4/golang-ical"
 	"github.com/stretchr/testify/assert"
@@ -116,6 +118,41 @@ func (s *TestSuite) TestMergeCals() {
 	}
 }
 
+// TestConflictReport tests the conflict report of an endpoint
+func (s *TestSuite) TestConflictReport() {
+	work, workSI, err := s.newSourceCalendar("work", []config.Rule{}, []config.Modifier{})
+	require.NoError(s.T(), err)
+	personal, personalSI, err := s.newSourceCalendar("personal", []config.Rule{}, []config.Modifier{})
+	require.NoError(s.T(), err)
+
+	start := now.Add(24 * time.Hour).Add(9 * time.Hour)
+	e := work.AddEvent("work-meeting")
+	e.SetStartAt(start)
+	e.SetEndAt(start.Add(time.Hour))
+	e = personal.AddEvent("dentist")
+	e.SetStartAt(start.Add(30 * time.Minute))
+	e.SetEndAt(start.Add(90 * time.Minute))
+
+	server := newTestCalServerFromSource(config.Source{
+		Name:      "conflicts",
+		EndPoint:  "conflicts",
+		Info:      []config.SourceInfo{workSI, personalSI},
+		Conflicts: config.Conflicts{Enabled: true},
+	})
+	defer server.Close()
+
+	resp, err := server.Server.Client().Get(server.Server.URL + "/" + server.Source.EndPoint + ".conflicts.json")
+	require.NoError(s.T(), err)
+	defer resp.Body.Close()
+	require.Equal(s.T(), http.StatusOK, resp.StatusCode)
+
+	var report ical2.ConflictReport
+	require.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&report))
+	require.Len(s.T(), report.Conflicts, 1)
+	assert.Equal(s.T(), "work-meeting", report.Conflicts[0].Events[0].UID)
+	assert.Equal(s.T(), "dentist", report.Conflicts[0].Events[1].UID)
+}
+
 func TestMain(t *testing.T) {
 	log.Init("ERROR", config.Notification{Service: "none"})
 	suite.Run(t, new(TestSuite))
@@ -130,11 +167,14 @@ type testCalServer struct {
 }
 
 func newTestCalServer(calName string, sources ...config.SourceInfo) testCalServer {
-	source := config.Source{
+	return newTestCalServerFromSource(config.Source{
 		Name:     calName,
 		EndPoint: calName,
 		Info:     sources,
-	}
+	})
+}
+
+func newTestCalServerFromSource(source config.Source) testCalServer {
 	mux := newServerMux(&config.Config{
 		Sources: []config.Source{
 			source,
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,8 +13,9 @@ import (
 )
 
 type ServerHandler struct {
-	cal   ical.CustomCalender
-	cache string
+	cal       ical.CustomCalender
+	cache     string
+	conflicts []byte
 }
 
 func NewServerHandler(source ical.CustomCalender) *ServerHandler {
@@ -31,9 +33,26 @@ func (sh *ServerHandler) updateCache() {
 		return
 	}
 	sh.cache = cal.Serialize()
+	if sh.cal.GetSource().Conflicts.Enabled {
+		sh.updateConflicts()
+	}
 	log.Logger.Notify(fmt.Sprintf("[%s] Merged ical files in %s", sh.cal.GetSource().Name, time.Since(now).String()))
 }
 
+func (sh *ServerHandler) updateConflicts() {
+	report := ical.ConflictReport{
+		Name:      sh.cal.GetSource().Name,
+		Generated: time.Now(),
+		Conflicts: sh.cal.Conflicts(),
+	}
+	b, e := json.Marshal(report)
+	if e != nil {
+		log.Logger.Error("Error marshalling conflict report", "error", e)
+		return
+	}
+	sh.conflicts = b
+}
+
 func (sh *ServerHandler) heartbeat() {
 	for range time.Tick(time.Minute * time.Duration(sh.cal.GetSource().Heartbeat)) {
 		sh.updateCache()
@@ -56,3 +75,14 @@ func (sh *ServerHandler) IcsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Logger.Info("Request complete", "elapsed_ms", time.Since(now).Milliseconds())
 	log.Logger.Notify(fmt.Sprintf("[%s] Served ics file in %s", sh.cal.GetSource().Name, time.Since(now).String()))
 }
+
+func (sh *ServerHandler) ConflictsHandler(w http.ResponseWriter, r *http.Request) {
+	if sh.conflicts == nil {
+		http.Error(w, "Conflict report not available", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if _, err := w.Write(sh.conflicts); err != nil {
+		log.Logger.Error("Error writing conflict report", "error", err)
+	}
+}